@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"crud-api/errores"
+
+	"github.com/gin-gonic/gin"
+)
+
+// peticionAPIKey es el cuerpo de POST /admin/apikeys
+type peticionAPIKey struct {
+	Nombre  string `json:"nombre" binding:"required,max=100"`
+	Alcance string `json:"alcance" binding:"required,oneof=lectura lectura_escritura"`
+}
+
+// vistaAPIKey es lo que se muestra de una clave; nunca incluye el hash
+type vistaAPIKey struct {
+	ID         string     `json:"id"`
+	Nombre     string     `json:"nombre"`
+	Alcance    string     `json:"alcance"`
+	Prefijo    string     `json:"prefijo"`
+	CreadaEn   time.Time  `json:"creada_en"`
+	CreadaPor  string     `json:"creada_por,omitempty"`
+	UltimoUso  *time.Time `json:"ultimo_uso,omitempty"`
+	RevocadaEn *time.Time `json:"revocada_en,omitempty"`
+}
+
+func nuevaVistaAPIKey(k APIKey) vistaAPIKey {
+	return vistaAPIKey{
+		ID:         k.ID,
+		Nombre:     k.Nombre,
+		Alcance:    k.Alcance,
+		Prefijo:    k.Prefijo,
+		CreadaEn:   k.CreadaEn,
+		CreadaPor:  k.CreadaPor,
+		UltimoUso:  k.UltimoUso,
+		RevocadaEn: k.RevocadaEn,
+	}
+}
+
+// CrearAPIKey - POST /admin/apikeys
+// Crea una clave; es la única respuesta que incluye la clave completa
+func (s *Servicio) CrearAPIKey(c *gin.Context) {
+	var datos peticionAPIKey
+	if err := c.ShouldBindJSON(&datos); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	var creadaPor string
+	if identidad, ok := IdentidadDe(c); ok {
+		creadaPor = identidad.Sujeto
+	}
+
+	secreto, clave, err := s.claves.Crear(datos.Nombre, datos.Alcance, creadaPor)
+	if err != nil {
+		c.Error(errores.Interno(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": secreto,
+		"clave":   nuevaVistaAPIKey(clave),
+	})
+}
+
+// ListarAPIKeys - GET /admin/apikeys
+// Retorna todas las claves, incluidas las revocadas
+func (s *Servicio) ListarAPIKeys(c *gin.Context) {
+	claves := s.claves.Listar()
+	vistas := make([]vistaAPIKey, len(claves))
+	for i, clave := range claves {
+		vistas[i] = nuevaVistaAPIKey(clave)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": vistas,
+		"total":    len(vistas),
+	})
+}
+
+// RevocarAPIKey - DELETE /admin/apikeys/:id
+// Revoca una clave; deja de aceptarse de inmediato
+func (s *Servicio) RevocarAPIKey(c *gin.Context) {
+	clave, err := s.claves.Revocar(c.Param("id"))
+	if errors.Is(err, ErrAPIKeyNoEncontrada) {
+		c.Error(errores.Nuevo(http.StatusNotFound, errores.CodigoNoEncontrado, "API key no encontrada"))
+		return
+	}
+	if err != nil {
+		c.Error(errores.Interno(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, nuevaVistaAPIKey(clave))
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Alcances de una API key
+const (
+	AlcanceLectura          = "lectura"
+	AlcanceLecturaEscritura = "lectura_escritura"
+)
+
+// prefijoAPIKey identifica las claves de esta API a simple vista
+const prefijoAPIKey = "crud_"
+
+// intervaloUltimoUso es cada cuánto como máximo se guarda en disco el
+// último uso de una clave; en memoria siempre está al día
+const intervaloUltimoUso = time.Minute
+
+var (
+	// ErrAPIKeyInvalida se retorna si la clave no existe o está revocada
+	ErrAPIKeyInvalida = errors.New("API key inválida o revocada")
+	// ErrAPIKeyNoEncontrada se retorna al revocar un ID que no existe
+	ErrAPIKeyNoEncontrada = errors.New("API key no encontrada")
+)
+
+// APIKey es una clave para clientes no interactivos. La clave en sí solo
+// se conoce al crearla; se guarda su hash SHA-256.
+type APIKey struct {
+	ID      string `json:"id"`
+	Nombre  string `json:"nombre"`
+	Alcance string `json:"alcance"`
+	// Prefijo son los primeros caracteres de la clave, para reconocerla
+	Prefijo    string     `json:"prefijo"`
+	Hash       string     `json:"hash"`
+	CreadaEn   time.Time  `json:"creada_en"`
+	CreadaPor  string     `json:"creada_por,omitempty"`
+	UltimoUso  *time.Time `json:"ultimo_uso,omitempty"`
+	RevocadaEn *time.Time `json:"revocada_en,omitempty"`
+}
+
+// Roles retorna los roles que otorga el alcance de la clave
+func (k APIKey) Roles() []string {
+	if k.Alcance == AlcanceLecturaEscritura {
+		return []string{RolEditor}
+	}
+	return []string{RolLector}
+}
+
+// AlcanceValido reporta si el alcance es uno de los conocidos
+func AlcanceValido(alcance string) bool {
+	return alcance == AlcanceLectura || alcance == AlcanceLecturaEscritura
+}
+
+// APIKeyStore guarda las API keys en memoria y, si tiene ruta, en un
+// archivo JSON
+type APIKeyStore struct {
+	mu       sync.Mutex
+	ruta     string
+	claves   map[string]*APIKey // por ID
+	porHash  map[string]*APIKey
+	guardado map[string]time.Time // último uso guardado en disco, por ID
+}
+
+// NuevoAPIKeyStore crea un almacén vacío que no persiste
+func NuevoAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		claves:   make(map[string]*APIKey),
+		porHash:  make(map[string]*APIKey),
+		guardado: make(map[string]time.Time),
+	}
+}
+
+// CargarAPIKeys lee las claves del archivo y guarda allí los cambios.
+// Si el archivo no existe retorna un almacén vacío.
+func CargarAPIKeys(ruta string) (*APIKeyStore, error) {
+	store := NuevoAPIKeyStore()
+	store.ruta = ruta
+
+	datos, err := os.ReadFile(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leer API keys: %w", err)
+	}
+
+	var claves []*APIKey
+	if err := json.Unmarshal(datos, &claves); err != nil {
+		return nil, fmt.Errorf("archivo de API keys inválido: %w", err)
+	}
+	for _, clave := range claves {
+		if clave.ID == "" || clave.Hash == "" || !AlcanceValido(clave.Alcance) {
+			return nil, fmt.Errorf("archivo de API keys inválido: clave %q incompleta", clave.ID)
+		}
+		store.claves[clave.ID] = clave
+		store.porHash[clave.Hash] = clave
+		if clave.UltimoUso != nil {
+			store.guardado[clave.ID] = *clave.UltimoUso
+		}
+	}
+	return store, nil
+}
+
+// Crear genera una clave nueva. Retorna la clave en texto plano, que no
+// se puede volver a obtener, y sus datos.
+func (s *APIKeyStore) Crear(nombre, alcance, creadaPor string) (string, APIKey, error) {
+	if !AlcanceValido(alcance) {
+		return "", APIKey{}, fmt.Errorf("alcance desconocido: %q", alcance)
+	}
+
+	aleatorio := make([]byte, 32)
+	if _, err := rand.Read(aleatorio); err != nil {
+		return "", APIKey{}, err
+	}
+	secreto := prefijoAPIKey + base64.RawURLEncoding.EncodeToString(aleatorio)
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", APIKey{}, err
+	}
+
+	clave := &APIKey{
+		ID:        hex.EncodeToString(id),
+		Nombre:    nombre,
+		Alcance:   alcance,
+		Prefijo:   secreto[:len(prefijoAPIKey)+6],
+		Hash:      hashAPIKey(secreto),
+		CreadaEn:  time.Now().UTC(),
+		CreadaPor: creadaPor,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claves[clave.ID] = clave
+	s.porHash[clave.Hash] = clave
+	if err := s.guardar(); err != nil {
+		delete(s.claves, clave.ID)
+		delete(s.porHash, clave.Hash)
+		return "", APIKey{}, err
+	}
+	return secreto, *clave, nil
+}
+
+// Listar retorna todas las claves, incluidas las revocadas, de la más
+// antigua a la más nueva
+func (s *APIKeyStore) Listar() []APIKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claves := make([]APIKey, 0, len(s.claves))
+	for _, clave := range s.claves {
+		claves = append(claves, *clave)
+	}
+	slices.SortFunc(claves, func(a, b APIKey) int {
+		return compararAPIKeys(&a, &b)
+	})
+	return claves
+}
+
+// Revocar deja de aceptar la clave. Revocar una clave ya revocada no
+// hace nada.
+func (s *APIKeyStore) Revocar(id string) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clave, ok := s.claves[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNoEncontrada
+	}
+	if clave.RevocadaEn != nil {
+		return *clave, nil
+	}
+
+	ahora := time.Now().UTC()
+	clave.RevocadaEn = &ahora
+	if err := s.guardar(); err != nil {
+		clave.RevocadaEn = nil
+		return APIKey{}, err
+	}
+	return *clave, nil
+}
+
+// Verificar retorna la clave si es válida y registra su uso
+func (s *APIKeyStore) Verificar(secreto string) (APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clave, ok := s.porHash[hashAPIKey(secreto)]
+	if !ok || clave.RevocadaEn != nil {
+		return APIKey{}, ErrAPIKeyInvalida
+	}
+
+	ahora := time.Now().UTC()
+	clave.UltimoUso = &ahora
+	// No escribir el archivo en cada petición
+	if ahora.Sub(s.guardado[clave.ID]) >= intervaloUltimoUso {
+		if err := s.guardar(); err != nil {
+			return APIKey{}, err
+		}
+	}
+	return *clave, nil
+}
+
+// Guardar escribe en el archivo los últimos usos que aún no se guardaron
+func (s *APIKeyStore) Guardar() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, clave := range s.claves {
+		if clave.UltimoUso != nil && !clave.UltimoUso.Equal(s.guardado[id]) {
+			return s.guardar()
+		}
+	}
+	return nil
+}
+
+// guardar escribe todas las claves en el archivo, reemplazándolo de forma
+// atómica. Debe llamarse con el mutex tomado.
+func (s *APIKeyStore) guardar() error {
+	if s.ruta == "" {
+		return nil
+	}
+
+	claves := make([]*APIKey, 0, len(s.claves))
+	for _, clave := range s.claves {
+		claves = append(claves, clave)
+	}
+	slices.SortFunc(claves, compararAPIKeys)
+	datos, err := json.MarshalIndent(claves, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporal, err := os.CreateTemp(filepath.Dir(s.ruta), filepath.Base(s.ruta)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("guardar API keys: %w", err)
+	}
+	defer os.Remove(temporal.Name())
+	if _, err := temporal.Write(datos); err != nil {
+		temporal.Close()
+		return fmt.Errorf("guardar API keys: %w", err)
+	}
+	if err := temporal.Close(); err != nil {
+		return fmt.Errorf("guardar API keys: %w", err)
+	}
+	if err := os.Rename(temporal.Name(), s.ruta); err != nil {
+		return fmt.Errorf("guardar API keys: %w", err)
+	}
+
+	for _, clave := range claves {
+		if clave.UltimoUso != nil {
+			s.guardado[clave.ID] = *clave.UltimoUso
+		}
+	}
+	return nil
+}
+
+// compararAPIKeys ordena por fecha de creación y luego por ID
+func compararAPIKeys(a, b *APIKey) int {
+	if c := a.CreadaEn.Compare(b.CreadaEn); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// hashAPIKey retorna el hash con el que se guarda una clave. Las claves
+// son aleatorias y largas, así que alcanza con SHA-256 (bcrypt sería
+// demasiado lento para verificar en cada petición).
+func hashAPIKey(secreto string) string {
+	suma := sha256.Sum256([]byte(secreto))
+	return hex.EncodeToString(suma[:])
+}
@@ -0,0 +1,70 @@
+// Package auth implementa la autenticación de la API: usuarios locales,
+// tokens JWT (HS256 y RS256), API keys para clientes no interactivos y
+// autorización por roles.
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"crud-api/errores"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Servicio junta el almacén de usuarios, el firmador de tokens y las
+// API keys
+type Servicio struct {
+	usuarios *UsuarioStore
+	tokens   *Tokens
+	claves   *APIKeyStore
+}
+
+// NuevoServicio crea el servicio de autenticación
+func NuevoServicio(usuarios *UsuarioStore, tokens *Tokens, claves *APIKeyStore) *Servicio {
+	return &Servicio{usuarios: usuarios, tokens: tokens, claves: claves}
+}
+
+// Cerrar guarda lo que quedó pendiente (el último uso de las API keys);
+// se llama al apagar el servidor
+func (s *Servicio) Cerrar() error {
+	return s.claves.Guardar()
+}
+
+// credenciales es el cuerpo de POST /auth/token
+type credenciales struct {
+	Usuario  string `json:"usuario" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login - POST /auth/token
+// Verifica usuario y contraseña y retorna un token de acceso
+func (s *Servicio) Login(c *gin.Context) {
+	var datos credenciales
+	if err := c.ShouldBindJSON(&datos); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	usuario, err := s.usuarios.Verificar(datos.Usuario, datos.Password)
+	if errors.Is(err, ErrCredencialesInvalidas) {
+		c.Error(errores.Nuevo(http.StatusUnauthorized, errores.CodigoCredencialesInvalidas, "Usuario o contraseña incorrectos"))
+		return
+	}
+	if err != nil {
+		c.Error(errores.Interno(err))
+		return
+	}
+
+	token, err := s.tokens.Emitir(usuario)
+	if err != nil {
+		c.Error(errores.Interno(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.tokens.Duracion().Seconds()),
+	})
+}
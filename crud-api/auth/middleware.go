@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"crud-api/errores"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claveIdentidad es la clave del contexto de Gin donde queda el cliente
+// autenticado
+const claveIdentidad = "auth.identidad"
+
+// HeaderAPIKey es el header con el que los clientes envían su API key
+const HeaderAPIKey = "X-API-Key"
+
+// sujetoAPIKey antecede al ID de la clave en el sujeto de las
+// identidades que vienen de una API key
+const sujetoAPIKey = "apikey:"
+
+// Identidad es quien hace la petición, ya autenticado
+type Identidad struct {
+	Sujeto string
+	Roles  []string
+}
+
+// EsAPIKey indica si la identidad viene de una API key
+func (i Identidad) EsAPIKey() bool {
+	return strings.HasPrefix(i.Sujeto, sujetoAPIKey)
+}
+
+// IdentidadDe retorna la identidad autenticada de la petición, si hay
+func IdentidadDe(c *gin.Context) (Identidad, bool) {
+	valor, ok := c.Get(claveIdentidad)
+	if !ok {
+		return Identidad{}, false
+	}
+	identidad, ok := valor.(Identidad)
+	return identidad, ok
+}
+
+// Identificar valida las credenciales de la petición, si trae, y guarda la
+// identidad en el contexto, pero deja pasar también a quien no trae o trae
+// credenciales inválidas. Va antes del limitador de peticiones, así solo
+// una API key ya verificada tiene su propio límite.
+func (s *Servicio) Identificar() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := IdentidadDe(c); !ok {
+			identidad, _, err := s.identificar(c)
+			if err != nil {
+				errores.Responder(c, errores.Interno(err))
+				return
+			}
+			if identidad != nil {
+				c.Set(claveIdentidad, *identidad)
+			}
+		}
+		c.Next()
+	}
+}
+
+// Autenticar exige un header "Authorization: Bearer <token>" o
+// "X-API-Key: <clave>" válido y guarda la identidad en el contexto. Si
+// Identificar ya aceptó las credenciales no las vuelve a verificar.
+func (s *Servicio) Autenticar() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := IdentidadDe(c); ok {
+			c.Next()
+			return
+		}
+
+		identidad, titulo, err := s.identificar(c)
+		if err != nil {
+			errores.Responder(c, errores.Interno(err))
+			return
+		}
+		if identidad == nil {
+			noAutenticado(c, titulo)
+			return
+		}
+
+		c.Set(claveIdentidad, *identidad)
+		c.Next()
+	}
+}
+
+// identificar verifica la API key o el token de la petición. Si faltan o
+// no son válidos retorna una identidad nil y el motivo.
+func (s *Servicio) identificar(c *gin.Context) (*Identidad, string, error) {
+	if secreto := c.GetHeader(HeaderAPIKey); secreto != "" {
+		return s.identificarAPIKey(secreto)
+	}
+
+	esquema, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(esquema, "Bearer") || token == "" {
+		return nil, "Falta el token de acceso", nil
+	}
+
+	claims, err := s.tokens.Verificar(strings.TrimSpace(token))
+	if err != nil {
+		return nil, "Token de acceso inválido o vencido", nil
+	}
+	return &Identidad{Sujeto: claims.Subject, Roles: claims.Roles}, "", nil
+}
+
+// identificarAPIKey valida la clave y retorna la identidad que otorga su
+// alcance
+func (s *Servicio) identificarAPIKey(secreto string) (*Identidad, string, error) {
+	clave, err := s.claves.Verificar(secreto)
+	if errors.Is(err, ErrAPIKeyInvalida) {
+		return nil, "API key inválida o revocada", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &Identidad{Sujeto: sujetoAPIKey + clave.ID, Roles: clave.Roles()}, "", nil
+}
+
+// RequerirRol deja pasar solo a identidades con alguno de los roles.
+// Debe ir después de Autenticar.
+func RequerirRol(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identidad, ok := IdentidadDe(c)
+		if !ok {
+			noAutenticado(c, "Falta el token de acceso")
+			return
+		}
+
+		if !(Usuario{Roles: identidad.Roles}).TieneRol(roles...) {
+			errores.Responder(c, errores.Nuevo(http.StatusForbidden, errores.CodigoSinPermiso, "No tiene permiso para esta operación").
+				ConDetalle("requiere alguno de los roles: %s", strings.Join(roles, ", ")))
+			return
+		}
+		c.Next()
+	}
+}
+
+// noAutenticado responde 401 indicando el esquema esperado
+func noAutenticado(c *gin.Context, titulo string) {
+	c.Header("WWW-Authenticate", `Bearer realm="crud-api"`)
+	errores.Responder(c, errores.Nuevo(http.StatusUnauthorized, errores.CodigoNoAutenticado, titulo))
+}
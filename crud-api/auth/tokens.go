@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// emisorToken es el "iss" de los tokens de esta API
+const emisorToken = "crud-api"
+
+// Algoritmos de firma soportados
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// Claims son los datos que lleva un token de acceso
+type Claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// ConfigTokens configura la firma y verificación de tokens
+type ConfigTokens struct {
+	// Algoritmo con el que se firman los tokens nuevos: AlgHS256 o AlgRS256
+	Algoritmo string
+	// Secreto para HS256 (al menos 32 bytes)
+	Secreto []byte
+	// ClavePrivada para RS256; su parte pública verifica los tokens
+	ClavePrivada *rsa.PrivateKey
+	// Duracion de cada token desde que se emite
+	Duracion time.Duration
+}
+
+// Tokens firma y verifica los JWT de la API. Firma con el algoritmo
+// configurado y acepta tokens HS256 y RS256 si tiene la clave de cada uno.
+type Tokens struct {
+	algoritmo string
+	duracion  time.Duration
+
+	secreto []byte          // HS256
+	privada *rsa.PrivateKey // RS256, para firmar
+	publica *rsa.PublicKey  // RS256, para verificar
+}
+
+// NuevoTokens valida la configuración y crea el firmador de tokens
+func NuevoTokens(cfg ConfigTokens) (*Tokens, error) {
+	if len(cfg.Secreto) > 0 && len(cfg.Secreto) < 32 {
+		return nil, errors.New("el secreto HS256 debe tener al menos 32 bytes")
+	}
+	if cfg.Duracion <= 0 {
+		return nil, errors.New("la duración de los tokens debe ser positiva")
+	}
+
+	t := &Tokens{algoritmo: cfg.Algoritmo, duracion: cfg.Duracion, secreto: cfg.Secreto}
+	if cfg.ClavePrivada != nil {
+		t.privada = cfg.ClavePrivada
+		t.publica = &cfg.ClavePrivada.PublicKey
+	}
+
+	switch cfg.Algoritmo {
+	case AlgHS256:
+		if len(t.secreto) == 0 {
+			return nil, errors.New("HS256 necesita un secreto")
+		}
+	case AlgRS256:
+		if t.privada == nil {
+			return nil, errors.New("RS256 necesita una clave privada")
+		}
+	default:
+		return nil, fmt.Errorf("algoritmo de firma no soportado: %q", cfg.Algoritmo)
+	}
+	return t, nil
+}
+
+// SecretoAleatorio genera un secreto HS256; los tokens firmados con él
+// dejan de valer al reiniciar el servidor
+func SecretoAleatorio() []byte {
+	secreto := make([]byte, 32)
+	if _, err := rand.Read(secreto); err != nil {
+		panic(err)
+	}
+	return secreto
+}
+
+// CargarClavePrivadaRSA lee una clave privada RSA en formato PEM
+func CargarClavePrivadaRSA(ruta string) (*rsa.PrivateKey, error) {
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("leer clave privada: %w", err)
+	}
+	clave, err := jwt.ParseRSAPrivateKeyFromPEM(datos)
+	if err != nil {
+		return nil, fmt.Errorf("clave privada inválida: %w", err)
+	}
+	return clave, nil
+}
+
+// Algoritmo retorna el algoritmo con el que se firman los tokens nuevos
+func (t *Tokens) Algoritmo() string {
+	return t.algoritmo
+}
+
+// Duracion retorna cuánto vale un token desde que se emite
+func (t *Tokens) Duracion() time.Duration {
+	return t.duracion
+}
+
+// Emitir firma un token de acceso para el usuario
+func (t *Tokens) Emitir(usuario Usuario) (string, error) {
+	ahora := time.Now()
+	claims := Claims{
+		Roles: usuario.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    emisorToken,
+			Subject:   usuario.Nombre,
+			IssuedAt:  jwt.NewNumericDate(ahora),
+			ExpiresAt: jwt.NewNumericDate(ahora.Add(t.duracion)),
+		},
+	}
+
+	if t.algoritmo == AlgRS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(t.privada)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secreto)
+}
+
+// Verificar valida la firma, el emisor y la expiración de un token
+func (t *Tokens) Verificar(token string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, t.clave,
+		jwt.WithValidMethods(t.metodosValidos()),
+		jwt.WithIssuer(emisorToken),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// clave elige la clave de verificación según el "alg" del token
+func (t *Tokens) clave(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case AlgHS256:
+		return t.secreto, nil
+	case AlgRS256:
+		return t.publica, nil
+	}
+	return nil, fmt.Errorf("algoritmo no soportado: %s", token.Method.Alg())
+}
+
+// metodosValidos lista los algoritmos para los que hay clave configurada,
+// así un token HS256 no se verifica nunca contra una clave vacía
+func (t *Tokens) metodosValidos() []string {
+	var metodos []string
+	if len(t.secreto) > 0 {
+		metodos = append(metodos, AlgHS256)
+	}
+	if t.publica != nil {
+		metodos = append(metodos, AlgRS256)
+	}
+	return metodos
+}
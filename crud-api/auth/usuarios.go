@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles de la API
+const (
+	RolAdmin  = "admin"
+	RolEditor = "editor"
+	RolLector = "lector"
+)
+
+// ErrCredencialesInvalidas se retorna si el usuario no existe o la
+// contraseña no coincide (no se distingue a propósito)
+var ErrCredencialesInvalidas = errors.New("usuario o contraseña incorrectos")
+
+// Usuario es una cuenta del almacén local
+type Usuario struct {
+	Nombre string `json:"usuario"`
+	// PasswordHash es el hash bcrypt de la contraseña
+	PasswordHash string   `json:"password_hash"`
+	Roles        []string `json:"roles"`
+}
+
+// TieneRol reporta si el usuario tiene alguno de los roles indicados
+func (u Usuario) TieneRol(roles ...string) bool {
+	for _, rol := range roles {
+		if slices.Contains(u.Roles, rol) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsuarioStore es el almacén local de usuarios, cargado de un archivo JSON
+type UsuarioStore struct {
+	mu       sync.RWMutex
+	usuarios map[string]Usuario
+}
+
+// NuevoUsuarioStore crea un almacén vacío
+func NuevoUsuarioStore() *UsuarioStore {
+	return &UsuarioStore{usuarios: make(map[string]Usuario)}
+}
+
+// CargarUsuarios lee los usuarios de un archivo JSON con una lista de
+// objetos {"usuario", "password_hash", "roles"}. Si el archivo no existe
+// retorna un almacén vacío.
+func CargarUsuarios(ruta string) (*UsuarioStore, error) {
+	store := NuevoUsuarioStore()
+
+	datos, err := os.ReadFile(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leer usuarios: %w", err)
+	}
+
+	var usuarios []Usuario
+	if err := json.Unmarshal(datos, &usuarios); err != nil {
+		return nil, fmt.Errorf("archivo de usuarios inválido: %w", err)
+	}
+	for _, usuario := range usuarios {
+		if usuario.Nombre == "" || usuario.PasswordHash == "" {
+			return nil, fmt.Errorf("archivo de usuarios inválido: cada usuario necesita usuario y password_hash")
+		}
+		store.usuarios[usuario.Nombre] = usuario
+	}
+	return store, nil
+}
+
+// Agregar crea o reemplaza un usuario con la contraseña en texto plano
+func (s *UsuarioStore) Agregar(nombre, password string, roles ...string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usuarios[nombre] = Usuario{Nombre: nombre, PasswordHash: hash, Roles: roles}
+	return nil
+}
+
+// Verificar retorna el usuario si la contraseña es correcta
+func (s *UsuarioStore) Verificar(nombre, password string) (Usuario, error) {
+	s.mu.RLock()
+	usuario, ok := s.usuarios[nombre]
+	s.mu.RUnlock()
+
+	if !ok {
+		// Comparar igual contra un hash fijo para no revelar por el tiempo
+		// de respuesta qué usuarios existen
+		bcrypt.CompareHashAndPassword(hashFalso, []byte(password))
+		return Usuario{}, ErrCredencialesInvalidas
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(usuario.PasswordHash), []byte(password)); err != nil {
+		return Usuario{}, ErrCredencialesInvalidas
+	}
+	return usuario, nil
+}
+
+// Cantidad retorna cuántos usuarios hay cargados
+func (s *UsuarioStore) Cantidad() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.usuarios)
+}
+
+// HashPassword genera el hash bcrypt para guardar en el archivo de usuarios
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+var hashFalso, _ = bcrypt.GenerateFromPassword([]byte("contraseña-inexistente"), bcrypt.DefaultCost)
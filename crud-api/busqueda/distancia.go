@@ -0,0 +1,42 @@
+package busqueda
+
+// distancia retorna la distancia de Damerau-Levenshtein (inserciones,
+// borrados, reemplazos y transposiciones de letras vecinas) entre a y b.
+// Si supera maximo retorna maximo+1 sin terminar el cálculo, porque solo
+// interesa saber si las palabras son parecidas.
+func distancia(a, b string, maximo int) int {
+	x, y := []rune(a), []rune(b)
+	if diferencia := len(x) - len(y); diferencia > maximo || -diferencia > maximo {
+		return maximo + 1
+	}
+
+	// Tres filas de la matriz: la anterior a la anterior (para las
+	// transposiciones), la anterior y la actual
+	antepenultima := make([]int, len(y)+1)
+	anterior := make([]int, len(y)+1)
+	actual := make([]int, len(y)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		actual[0] = i
+		minimoFila := actual[0]
+		for j := 1; j <= len(y); j++ {
+			costo := 1
+			if x[i-1] == y[j-1] {
+				costo = 0
+			}
+			actual[j] = min(anterior[j]+1, actual[j-1]+1, anterior[j-1]+costo)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				actual[j] = min(actual[j], antepenultima[j-2]+1)
+			}
+			minimoFila = min(minimoFila, actual[j])
+		}
+		if minimoFila > maximo {
+			return maximo + 1
+		}
+		antepenultima, anterior, actual = anterior, actual, antepenultima
+	}
+	return anterior[len(y)]
+}
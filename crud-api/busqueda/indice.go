@@ -0,0 +1,284 @@
+// Package busqueda mantiene un índice invertido en memoria sobre el nombre
+// y la descripción de los productos y lo consulta con ranking BM25,
+// prefijos y tolerancia a errores de tipeo.
+package busqueda
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"crud-api/models"
+)
+
+const (
+	// k1 y b son los parámetros usuales de BM25: k1 satura la frecuencia
+	// de un término y b normaliza por la longitud del producto
+	k1 = 1.2
+	b  = 0.75
+
+	// pesoNombre cuenta cada término del nombre como varias apariciones,
+	// así un producto que se llama como lo buscado queda antes que uno
+	// que solo lo menciona en la descripción
+	pesoNombre = 3
+
+	// factorPrefijo y factorDifuso reducen el puntaje de los términos que
+	// no coinciden exactamente con lo buscado
+	factorPrefijo = 0.8
+	factorDifuso  = 0.5
+
+	// largoMinimoPrefijo es desde cuántas letras una palabra de la
+	// consulta encuentra también las que empiezan con ella; con "*" al
+	// final vale con cualquier largo
+	largoMinimoPrefijo = 3
+	// largoMinimoDifuso es desde cuántas letras se toleran errores
+	largoMinimoDifuso = 4
+
+	// candadosSincronizar es cuántos locks reparten los IDs en Sincronizar
+	candadosSincronizar = 64
+)
+
+// Resultado es un producto encontrado con su puntaje; más alto es más
+// relevante
+type Resultado struct {
+	ID      int
+	Puntaje float64
+}
+
+// Indice es un índice invertido de productos. Es seguro para uso
+// concurrente; los handlers lo actualizan con Sincronizar después de cada
+// escritura.
+type Indice struct {
+	mu sync.RWMutex
+	// postings guarda, para cada término, la frecuencia ponderada en cada
+	// producto que lo contiene
+	postings map[string]map[int]int
+	// terminos guarda los términos de cada producto, para quitarlo
+	terminos map[int][]string
+	// longitudes es la cantidad ponderada de términos de cada producto
+	longitudes    map[int]int
+	longitudTotal int
+
+	// candados serializan Sincronizar para un mismo ID (repartidos por
+	// ID módulo la cantidad, para no guardar uno por producto)
+	candados [candadosSincronizar]sync.Mutex
+}
+
+// NuevoIndice crea un índice vacío
+func NuevoIndice() *Indice {
+	return &Indice{
+		postings:   make(map[string]map[int]int),
+		terminos:   make(map[int][]string),
+		longitudes: make(map[int]int),
+	}
+}
+
+// Indexar crea un índice con los productos que recorre la función, que
+// suele ser el Each de un repositorio
+func Indexar(recorrer func(func(models.Producto) error) error) (*Indice, error) {
+	indice := NuevoIndice()
+	err := recorrer(func(producto models.Producto) error {
+		indice.Agregar(producto)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return indice, nil
+}
+
+// Agregar indexa un producto; si ya estaba, reemplaza lo indexado. Un
+// producto de la papelera se quita, porque no aparece en las búsquedas.
+func (i *Indice) Agregar(producto models.Producto) {
+	if producto.EliminadoEn != nil {
+		i.Quitar(producto.ID)
+		return
+	}
+
+	frecuencias := map[string]int{}
+	for _, termino := range Tokenizar(producto.Nombre) {
+		frecuencias[termino] += pesoNombre
+	}
+	for _, termino := range Tokenizar(producto.Descripcion) {
+		frecuencias[termino]++
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.quitar(producto.ID)
+	longitud := 0
+	terminos := make([]string, 0, len(frecuencias))
+	for termino, frecuencia := range frecuencias {
+		if i.postings[termino] == nil {
+			i.postings[termino] = make(map[int]int)
+		}
+		i.postings[termino][producto.ID] = frecuencia
+		terminos = append(terminos, termino)
+		longitud += frecuencia
+	}
+	i.terminos[producto.ID] = terminos
+	i.longitudes[producto.ID] = longitud
+	i.longitudTotal += longitud
+}
+
+// Sincronizar vuelve a indexar un producto con lo que obtener retorna en
+// este momento, normalmente leyéndolo del repositorio; si no existe lo
+// quita. Las llamadas para un mismo ID se serializan, así la última lee la
+// última versión aunque dos escrituras concurrentes terminen en otro orden.
+func (i *Indice) Sincronizar(id int, obtener func(id int) (producto models.Producto, existe bool, err error)) error {
+	candado := &i.candados[id%candadosSincronizar]
+	candado.Lock()
+	defer candado.Unlock()
+
+	producto, existe, err := obtener(id)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		i.Quitar(id)
+		return nil
+	}
+	i.Agregar(producto)
+	return nil
+}
+
+// Quitar saca un producto del índice
+func (i *Indice) Quitar(id int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.quitar(id)
+}
+
+// quitar debe llamarse con el Lock tomado
+func (i *Indice) quitar(id int) {
+	for _, termino := range i.terminos[id] {
+		delete(i.postings[termino], id)
+		if len(i.postings[termino]) == 0 {
+			delete(i.postings, termino)
+		}
+	}
+	i.longitudTotal -= i.longitudes[id]
+	delete(i.terminos, id)
+	delete(i.longitudes, id)
+}
+
+// Buscar retorna los productos que contienen alguna palabra de la consulta,
+// del más relevante al menos (a igual puntaje, por ID). Cada palabra
+// encuentra el término exacto, los que empiezan con ella y, si difuso es
+// true, los que difieren en una letra (dos desde ocho letras).
+// Un producto suma el puntaje BM25 de cada palabra que contiene.
+func (i *Indice) Buscar(consulta string, difuso bool) []Resultado {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	puntajes := map[int]float64{}
+	vistas := map[string]bool{}
+	for _, palabra := range tokenizar(consulta, true) {
+		if vistas[palabra] {
+			continue
+		}
+		vistas[palabra] = true
+
+		// Los términos que encuentra una palabra comparten su IDF, calculado
+		// con todos los productos que la contienen; si no, un prefijo poco
+		// común como "camiones" puntuaría más que el exacto "camion"
+		terminos := i.expandir(palabra, difuso)
+		conPalabra := map[int]bool{}
+		for termino := range terminos {
+			for id := range i.postings[termino] {
+				conPalabra[id] = true
+			}
+		}
+		idf := i.idf(len(conPalabra))
+
+		// Para cada producto cuenta la mejor coincidencia de la palabra,
+		// así "camion" no suma dos veces en uno que dice "camión camiones"
+		mejores := map[int]float64{}
+		for termino, factor := range terminos {
+			for id, puntaje := range i.bm25(termino, idf) {
+				mejores[id] = max(mejores[id], factor*puntaje)
+			}
+		}
+		for id, puntaje := range mejores {
+			puntajes[id] += puntaje
+		}
+	}
+
+	resultados := make([]Resultado, 0, len(puntajes))
+	for id, puntaje := range puntajes {
+		resultados = append(resultados, Resultado{ID: id, Puntaje: puntaje})
+	}
+	sort.Slice(resultados, func(a, b int) bool {
+		if resultados[a].Puntaje != resultados[b].Puntaje {
+			return resultados[a].Puntaje > resultados[b].Puntaje
+		}
+		return resultados[a].ID < resultados[b].ID
+	})
+	return resultados
+}
+
+// expandir retorna los términos del índice que coinciden con una palabra
+// de la consulta y el factor que se aplica a su puntaje. Debe llamarse con
+// algún lock tomado.
+func (i *Indice) expandir(palabra string, difuso bool) map[string]float64 {
+	palabra, comodin := strings.CutSuffix(palabra, "*")
+	largo := utf8.RuneCountInString(palabra)
+	prefijo := comodin || largo >= largoMinimoPrefijo
+	tolerancia := 0
+	if difuso && !comodin && largo >= largoMinimoDifuso {
+		tolerancia = 1
+		if largo >= 8 {
+			tolerancia = 2
+		}
+	}
+
+	terminos := map[string]float64{}
+	if _, ok := i.postings[palabra]; ok {
+		terminos[palabra] = 1
+	}
+	if !prefijo && tolerancia == 0 {
+		return terminos
+	}
+
+	for termino := range i.postings {
+		if termino == palabra {
+			continue
+		}
+		if prefijo && strings.HasPrefix(termino, palabra) {
+			terminos[termino] = factorPrefijo
+			continue
+		}
+		if tolerancia > 0 {
+			if d := distancia(palabra, termino, tolerancia); d <= tolerancia {
+				terminos[termino] = factorDifuso / float64(d)
+			}
+		}
+	}
+	return terminos
+}
+
+// idf es la rareza de una palabra que está en cantidad productos. Debe
+// llamarse con algún lock tomado.
+func (i *Indice) idf(cantidad int) float64 {
+	total, n := float64(len(i.longitudes)), float64(cantidad)
+	return math.Log(1 + (total-n+0.5)/(n+0.5))
+}
+
+// bm25 retorna el puntaje de un término en cada producto que lo contiene.
+// Debe llamarse con algún lock tomado.
+func (i *Indice) bm25(termino string, idf float64) map[int]float64 {
+	productos := i.postings[termino]
+	promedio := float64(i.longitudTotal) / float64(len(i.longitudes))
+
+	puntajes := make(map[int]float64, len(productos))
+	for id, frecuencia := range productos {
+		tf := float64(frecuencia)
+		normalizacion := 1 - b + b*float64(i.longitudes[id])/promedio
+		puntajes[id] = idf * tf * (k1 + 1) / (tf + k1*normalizacion)
+	}
+	return puntajes
+}
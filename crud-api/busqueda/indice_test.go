@@ -0,0 +1,84 @@
+package busqueda_test
+
+import (
+	"sync"
+	"testing"
+
+	"crud-api/busqueda"
+	"crud-api/models"
+)
+
+// TestSincronizarIndexaLaUltimaVersion reproduce dos escrituras del mismo
+// producto que terminan en otro orden: la primera en guardarse es la
+// última en actualizar el índice. El índice tiene que quedar con lo que
+// está guardado, no con lo que escribió la primera.
+func TestSincronizarIndexaLaUltimaVersion(t *testing.T) {
+	var mu sync.Mutex
+	guardado := models.Producto{ID: 1}
+	guardar := func(nombre string) {
+		mu.Lock()
+		defer mu.Unlock()
+		guardado.Nombre = nombre
+	}
+	obtener := func(id int) (models.Producto, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return guardado, true, nil
+	}
+
+	indice := busqueda.NuevoIndice()
+	guardar("alfa")                                        // escritura A
+	guardar("bravo")                                       // escritura B
+	if err := indice.Sincronizar(1, obtener); err != nil { // B actualiza el índice
+		t.Fatal(err)
+	}
+	if err := indice.Sincronizar(1, obtener); err != nil { // A lo actualiza tarde
+		t.Fatal(err)
+	}
+
+	if resultados := indice.Buscar("alfa", false); len(resultados) != 0 {
+		t.Errorf("alfa: %v, el índice quedó con una versión vieja", resultados)
+	}
+	if resultados := indice.Buscar("bravo", false); len(resultados) != 1 {
+		t.Errorf("bravo: %v, se esperaba el producto 1", resultados)
+	}
+}
+
+// TestSincronizarConcurrente sincroniza el mismo producto desde muchas
+// goroutines mientras cambia; al terminar el índice refleja lo guardado
+func TestSincronizarConcurrente(t *testing.T) {
+	var mu sync.Mutex
+	guardado := models.Producto{ID: 7}
+	obtener := func(id int) (models.Producto, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return guardado, guardado.Nombre != "", nil
+	}
+
+	indice := busqueda.NuevoIndice()
+	nombres := []string{"alfa", "bravo", "charlie", "delta", ""}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			mu.Lock()
+			guardado.Nombre = nombres[i%len(nombres)]
+			mu.Unlock()
+			if err := indice.Sincronizar(7, obtener); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, nombre := range nombres[:len(nombres)-1] {
+		esperado := 0
+		if nombre == guardado.Nombre {
+			esperado = 1
+		}
+		if resultados := indice.Buscar(nombre, false); len(resultados) != esperado {
+			t.Errorf("%s: %d resultados, se esperaban %d (guardado: %q)", nombre, len(resultados), esperado, guardado.Nombre)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package busqueda
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sinAcentos lleva cada letra acentuada a la letra sin acento, así
+// "camion" encuentra "camión". La ñ también se pliega porque muchos
+// teclados no la tienen.
+var sinAcentos = strings.NewReplacer(
+	"á", "a", "à", "a", "ä", "a", "â", "a", "ã", "a",
+	"é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ì", "i", "ï", "i", "î", "i",
+	"ó", "o", "ò", "o", "ö", "o", "ô", "o", "õ", "o",
+	"ú", "u", "ù", "u", "ü", "u", "û", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// palabrasVacias son palabras del español tan comunes que no ayudan a
+// distinguir un producto de otro
+var palabrasVacias = func() map[string]bool {
+	palabras := map[string]bool{}
+	for _, palabra := range strings.Fields(`
+		a al ante con contra de del desde e el en entre es la las lo los
+		o para por que se sin sobre su sus u un una unas unos y`) {
+		palabras[palabra] = true
+	}
+	return palabras
+}()
+
+// Tokenizar separa un texto en términos: en minúsculas, sin acentos y sin
+// palabras vacías. Se usa igual para indexar y para consultar.
+func Tokenizar(texto string) []string {
+	return tokenizar(texto, false)
+}
+
+// tokenizar es Tokenizar; con comodines conserva un "*" final, que en las
+// consultas marca un prefijo
+func tokenizar(texto string, comodines bool) []string {
+	texto = sinAcentos.Replace(strings.ToLower(texto))
+	separador := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && (!comodines || r != '*')
+	}
+
+	var terminos []string
+	for _, palabra := range strings.FieldsFunc(texto, separador) {
+		// Un "*" solo vale al final de la palabra
+		prefijo := strings.HasSuffix(palabra, "*")
+		palabra = strings.ReplaceAll(palabra, "*", "")
+		if palabra == "" || (palabrasVacias[palabra] && !prefijo) {
+			continue
+		}
+		if prefijo {
+			palabra += "*"
+		}
+		terminos = append(terminos, palabra)
+	}
+	return terminos
+}
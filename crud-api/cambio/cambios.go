@@ -0,0 +1,117 @@
+package cambio
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrSinArchivo indica que no se configuró un archivo de tipos de cambio
+var ErrSinArchivo = errors.New("no hay archivo de tipos de cambio configurado")
+
+// Cambios mantiene la tabla vigente. Se recarga con Recargar (desde una
+// señal o un endpoint de administración) y, si se indica un intervalo,
+// cada vez que cambia la fecha de modificación del archivo. Si una recarga
+// falla se sigue usando la tabla anterior.
+type Cambios struct {
+	ruta string
+
+	mu         sync.RWMutex
+	tabla      *Tabla
+	modificado time.Time
+
+	detener chan struct{}
+	hecho   chan struct{}
+}
+
+// Abrir carga el archivo de tipos de cambio. Con ruta vacía no hay tabla
+// y solo se pueden usar los precios fijos de cada producto.
+func Abrir(ruta string, intervalo time.Duration) (*Cambios, error) {
+	c := &Cambios{ruta: ruta, detener: make(chan struct{}), hecho: make(chan struct{})}
+	if ruta == "" {
+		close(c.hecho)
+		return c, nil
+	}
+
+	if _, err := c.Recargar(); err != nil {
+		return nil, err
+	}
+
+	if intervalo > 0 {
+		go c.vigilar(intervalo)
+	} else {
+		close(c.hecho)
+	}
+	return c, nil
+}
+
+// Tabla retorna la tabla vigente, o nil si no hay archivo configurado
+func (c *Cambios) Tabla() *Tabla {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tabla
+}
+
+// Recargar vuelve a leer el archivo y, si es válido, reemplaza la tabla
+func (c *Cambios) Recargar() (*Tabla, error) {
+	if c.ruta == "" {
+		return nil, ErrSinArchivo
+	}
+
+	// La fecha se toma antes de leer: si el archivo cambia mientras se
+	// lee, la próxima revisión lo vuelve a cargar
+	info, err := os.Stat(c.ruta)
+	if err != nil {
+		return nil, err
+	}
+	tabla, err := Cargar(c.ruta)
+
+	// Un archivo inválido también cuenta como leído, para no volver a
+	// intentarlo (ni registrar el error) en cada revisión hasta que cambie
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modificado = info.ModTime()
+	if err != nil {
+		return nil, err
+	}
+	c.tabla = tabla
+	return tabla, nil
+}
+
+// vigilar recarga el archivo cuando cambia su fecha de modificación,
+// revisando cada intervalo hasta Close
+func (c *Cambios) vigilar(intervalo time.Duration) {
+	defer close(c.hecho)
+
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(c.ruta)
+			c.mu.RLock()
+			sinCambios := err == nil && info.ModTime().Equal(c.modificado)
+			c.mu.RUnlock()
+			if sinCambios {
+				continue
+			}
+			if tabla, err := c.Recargar(); err != nil {
+				slog.Error("error al recargar los tipos de cambio; se sigue usando la tabla anterior", "error", err)
+			} else {
+				slog.Info("tipos de cambio recargados", "fecha", tabla.Fecha, "huella", tabla.Huella)
+			}
+		case <-c.detener:
+			return
+		}
+	}
+}
+
+// Close detiene la revisión periódica del archivo
+func (c *Cambios) Close() error {
+	close(c.detener)
+	<-c.hecho
+	return nil
+}
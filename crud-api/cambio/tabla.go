@@ -0,0 +1,193 @@
+// Package cambio carga la tabla de tipos de cambio desde un archivo JSON,
+// la recarga cuando cambia y convierte precios entre monedas sin pasar por
+// float64.
+package cambio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"crud-api/dinero"
+	"crud-api/models"
+)
+
+// ErrSinTasa indica que la tabla no tiene alguna de las monedas
+var ErrSinTasa = errors.New("no hay tipo de cambio")
+
+// archivoTabla es el formato del archivo de tipos de cambio:
+//
+//	{"base": "USD", "fecha": "2026-10-18T00:00:00Z",
+//	 "tasas": {"EUR": "0.9215", "ARS": "980.50"}}
+//
+// Cada tasa es cuántas unidades de la moneda vale una de la base; se
+// admiten como texto o como número y se leen de forma exacta.
+type archivoTabla struct {
+	Base  string                     `json:"base"`
+	Fecha time.Time                  `json:"fecha"`
+	Tasas map[string]json.RawMessage `json:"tasas"`
+}
+
+// Tabla es un conjunto de tipos de cambio respecto de una moneda base
+type Tabla struct {
+	// Base es la moneda en la que se expresan las tasas
+	Base string
+	// Fecha es la de las cotizaciones, según el archivo
+	Fecha time.Time
+	// CargadaEn es cuándo se leyó el archivo
+	CargadaEn time.Time
+	// Huella identifica el contenido del archivo; cambia si cambia alguna tasa
+	Huella string
+
+	tasas map[string]*big.Rat
+}
+
+// Cargar lee la tabla de un archivo
+func Cargar(ruta string) (*Tabla, error) {
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("leer tipos de cambio: %w", err)
+	}
+	tabla, err := Leer(datos)
+	if err != nil {
+		return nil, fmt.Errorf("tipos de cambio %s: %w", ruta, err)
+	}
+	return tabla, nil
+}
+
+// Leer interpreta el contenido de un archivo de tipos de cambio y valida
+// que las monedas existan y las tasas sean positivas
+func Leer(datos []byte) (*Tabla, error) {
+	decoder := json.NewDecoder(bytes.NewReader(datos))
+	decoder.DisallowUnknownFields()
+	var archivo archivoTabla
+	if err := decoder.Decode(&archivo); err != nil {
+		return nil, err
+	}
+
+	if _, ok := dinero.Decimales(archivo.Base); !ok {
+		return nil, fmt.Errorf("base: %q %w", archivo.Base, dinero.ErrMonedaDesconocida)
+	}
+	if archivo.Fecha.IsZero() {
+		return nil, errors.New("fecha: es obligatoria")
+	}
+
+	suma := sha256.Sum256(datos)
+	tabla := &Tabla{
+		Base:      archivo.Base,
+		Fecha:     archivo.Fecha,
+		CargadaEn: time.Now().UTC(),
+		Huella:    hex.EncodeToString(suma[:8]),
+		tasas:     map[string]*big.Rat{archivo.Base: big.NewRat(1, 1)},
+	}
+	for moneda, crudo := range archivo.Tasas {
+		if _, ok := dinero.Decimales(moneda); !ok {
+			return nil, fmt.Errorf("tasas: %q %w", moneda, dinero.ErrMonedaDesconocida)
+		}
+		tasa, ok := new(big.Rat).SetString(strings.Trim(string(crudo), `"`))
+		if !ok || tasa.Sign() <= 0 {
+			return nil, fmt.Errorf("tasas.%s: debe ser un número mayor que 0", moneda)
+		}
+		if moneda == archivo.Base && tasa.Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, fmt.Errorf("tasas.%s: la moneda base vale 1", moneda)
+		}
+		tabla.tasas[moneda] = tasa
+	}
+	return tabla, nil
+}
+
+// Tasa retorna cuántas unidades de hacia vale una unidad de desde
+func (t *Tabla) Tasa(desde, hacia string) (*big.Rat, error) {
+	origen, ok := t.tasas[desde]
+	if !ok {
+		return nil, fmt.Errorf("%w para %s", ErrSinTasa, desde)
+	}
+	destino, ok := t.tasas[hacia]
+	if !ok {
+		return nil, fmt.Errorf("%w para %s", ErrSinTasa, hacia)
+	}
+	return new(big.Rat).Quo(destino, origen), nil
+}
+
+// Convertir pasa un importe a otra moneda. El resultado se redondea a los
+// decimales de la moneda destino con redondeo bancario.
+func (t *Tabla) Convertir(importe dinero.Dinero, hacia string) (dinero.Dinero, *big.Rat, error) {
+	tasa, err := t.Tasa(importe.Moneda(), hacia)
+	if err != nil {
+		return dinero.Dinero{}, nil, err
+	}
+	convertido, err := dinero.DesdeRat(new(big.Rat).Mul(importe.Rat(), tasa), hacia, dinero.MitadPar)
+	if err != nil {
+		return dinero.Dinero{}, nil, err
+	}
+	return convertido, tasa, nil
+}
+
+// EnMoneda retorna el producto con el precio en la moneda pedida: el
+// precio fijado para esa moneda si lo tiene o, si no, el convertido con la
+// tabla, con el detalle en Conversion. tabla puede ser nil si no hay tipos
+// de cambio configurados; entonces solo sirven los precios fijos.
+func EnMoneda(tabla *Tabla, producto models.Producto, moneda string) (models.Producto, error) {
+	if producto.Precio.Moneda() == moneda {
+		return producto, nil
+	}
+	if fijo, ok := producto.Precios[moneda]; ok {
+		producto.Precios = cambiarPrincipal(producto, fijo)
+		producto.Precio = fijo
+		return producto, nil
+	}
+	if tabla == nil {
+		return models.Producto{}, fmt.Errorf("%w de %s a %s: no hay tabla de tipos de cambio configurada",
+			ErrSinTasa, producto.Precio.Moneda(), moneda)
+	}
+
+	convertido, tasa, err := tabla.Convertir(producto.Precio, moneda)
+	if err != nil {
+		return models.Producto{}, err
+	}
+	producto.Conversion = &models.Conversion{
+		PrecioOriginal: producto.Precio,
+		MonedaOriginal: producto.Precio.Moneda(),
+		Tasa:           FormatearTasa(tasa),
+		Fecha:          tabla.Fecha,
+	}
+	producto.Precio = convertido
+	return producto, nil
+}
+
+// cambiarPrincipal retorna los precios fijos del producto con el precio
+// principal en lugar de fijo, así la respuesta sigue teniendo todos los
+// precios y ninguno repetido
+func cambiarPrincipal(producto models.Producto, fijo dinero.Dinero) models.Precios {
+	precios := make(models.Precios, len(producto.Precios))
+	for moneda, precio := range producto.Precios {
+		if moneda != fijo.Moneda() {
+			precios[moneda] = precio
+		}
+	}
+	precios[producto.Precio.Moneda()] = producto.Precio
+	return precios
+}
+
+// FormatearTasa escribe una tasa con hasta 8 decimales, sin ceros de más
+func FormatearTasa(tasa *big.Rat) string {
+	texto := tasa.FloatString(8)
+	texto = strings.TrimRight(texto, "0")
+	return strings.TrimSuffix(texto, ".")
+}
+
+// Monedas retorna las tasas respecto de la base, por moneda, como texto
+func (t *Tabla) Monedas() map[string]string {
+	tasas := make(map[string]string, len(t.tasas))
+	for moneda, tasa := range t.tasas {
+		tasas[moneda] = FormatearTasa(tasa)
+	}
+	return tasas
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvArchivo es la variable de entorno con la ruta del archivo YAML, si no
+// se usa el flag -config
+const EnvArchivo = "CRUD_CONFIG"
+
+// opcion es un valor que se puede configurar con un flag y una variable
+// de entorno. campo retorna un puntero al valor dentro de la Config.
+type opcion struct {
+	flag  string
+	env   string
+	uso   string
+	campo func(*Config) any
+}
+
+// opciones son todos los valores configurables por flag y entorno. Los
+// nombres de flags y variables anteriores a esta capa se mantienen.
+var opciones = []opcion{
+	{"addr", "CRUD_ADDR", "dirección donde escucha el servidor", func(c *Config) any { return &c.Servidor.Direccion }},
+	{"modo", "GIN_MODE", "modo de Gin: debug, release o test", func(c *Config) any { return &c.Servidor.Modo }},
+	{"tls-cert", "CRUD_TLS_CERT", "certificado TLS en PEM", func(c *Config) any { return &c.Servidor.TLS.Certificado }},
+	{"tls-clave", "CRUD_TLS_KEY", "clave privada TLS en PEM", func(c *Config) any { return &c.Servidor.TLS.Clave }},
+	{"timeout-lectura", "CRUD_READ_TIMEOUT", "tiempo máximo para leer una petición completa", func(c *Config) any { return &c.Servidor.Timeouts.Lectura }},
+	{"timeout-encabezados", "CRUD_READ_HEADER_TIMEOUT", "tiempo máximo para leer los encabezados", func(c *Config) any { return &c.Servidor.Timeouts.LecturaEncabezados }},
+	{"timeout-escritura", "CRUD_WRITE_TIMEOUT", "tiempo máximo para escribir una respuesta", func(c *Config) any { return &c.Servidor.Timeouts.Escritura }},
+	{"timeout-inactividad", "CRUD_IDLE_TIMEOUT", "tiempo máximo de una conexión keep-alive sin uso", func(c *Config) any { return &c.Servidor.Timeouts.Inactividad }},
+	{"timeout-apagado", "CRUD_SHUTDOWN_TIMEOUT", "cuánto se esperan las peticiones en curso al apagar", func(c *Config) any { return &c.Servidor.Timeouts.Apagado }},
+	{"proxies-confiables", "CRUD_TRUSTED_PROXIES", "IPs o CIDRs de proxies cuyo X-Forwarded-For se acepta, separados por coma", func(c *Config) any { return &c.Servidor.ProxiesConfiables }},
+
+	{"storage", "CRUD_STORAGE", "backend de almacenamiento: memoria, sqlite o archivo", func(c *Config) any { return &c.Almacenamiento.Backend }},
+	{"db", "CRUD_DB_PATH", "ruta del archivo SQLite", func(c *Config) any { return &c.Almacenamiento.SQLite }},
+	{"data", "CRUD_DATA_DIR", "directorio del log y snapshot (backend archivo)", func(c *Config) any { return &c.Almacenamiento.Directorio }},
+	{"compactar", "CRUD_COMPACT_INTERVAL", "intervalo de compactación del log (backend archivo)", func(c *Config) any { return &c.Almacenamiento.Compactacion }},
+
+	{"log-nivel", "CRUD_LOG_LEVEL", "nivel de log: debug, info, warn o error", func(c *Config) any { return &c.Log.Nivel }},
+	{"log-formato", "CRUD_LOG_FORMAT", "formato de los logs: json o texto", func(c *Config) any { return &c.Log.Formato }},
+	{"cors-origenes", "CRUD_CORS_ORIGINS", "orígenes permitidos por CORS, separados por coma (\"*\" permite todos)", func(c *Config) any { return &c.CORS.Origenes }},
+
+	{"jwt-alg", "CRUD_JWT_ALG", "algoritmo de firma de los tokens: HS256 o RS256", func(c *Config) any { return &c.Auth.Algoritmo }},
+	{"jwt-secreto", "CRUD_JWT_SECRET", "secreto HS256 (al menos 32 bytes)", func(c *Config) any { return &c.Auth.Secreto }},
+	{"jwt-clave-privada", "CRUD_JWT_PRIVATE_KEY", "ruta de la clave privada RSA en PEM (RS256)", func(c *Config) any { return &c.Auth.ClavePrivada }},
+	{"jwt-duracion", "CRUD_JWT_DURATION", "duración de los tokens de acceso", func(c *Config) any { return &c.Auth.Duracion }},
+	{"usuarios", "CRUD_USUARIOS", "archivo JSON con los usuarios", func(c *Config) any { return &c.Auth.Usuarios }},
+	{"apikeys", "CRUD_APIKEYS", "archivo JSON donde se guardan las API keys", func(c *Config) any { return &c.Auth.APIKeys }},
+
+	{"limite-lectura", "CRUD_RATE_READ", "peticiones por segundo por cliente en las lecturas", func(c *Config) any { return &c.Limites.Lectura.Tasa }},
+	{"rafaga-lectura", "CRUD_BURST_READ", "ráfaga máxima por cliente en las lecturas", func(c *Config) any { return &c.Limites.Lectura.Rafaga }},
+	{"limite-escritura", "CRUD_RATE_WRITE", "peticiones por segundo por cliente en las modificaciones", func(c *Config) any { return &c.Limites.Escritura.Tasa }},
+	{"rafaga-escritura", "CRUD_BURST_WRITE", "ráfaga máxima por cliente en las modificaciones", func(c *Config) any { return &c.Limites.Escritura.Rafaga }},
+	{"limite-auth", "CRUD_RATE_AUTH", "peticiones por segundo por cliente en POST /auth/token", func(c *Config) any { return &c.Limites.Auth.Tasa }},
+	{"rafaga-auth", "CRUD_BURST_AUTH", "ráfaga máxima por cliente en POST /auth/token", func(c *Config) any { return &c.Limites.Auth.Rafaga }},
+
+	{"tipos-cambio", "CRUD_EXCHANGE_RATES", "archivo JSON con los tipos de cambio para ?currency=", func(c *Config) any { return &c.Cambio.Archivo }},
+	{"tipos-cambio-recarga", "CRUD_EXCHANGE_RATES_RELOAD", "cada cuánto se revisa si cambió el archivo de tipos de cambio (0 desactiva)", func(c *Config) any { return &c.Cambio.Recarga }},
+
+	{"papelera-retencion", "CRUD_TRASH_RETENTION", "cuánto se pueden restaurar los productos eliminados antes de purgarlos (0 no purga)", func(c *Config) any { return &c.Papelera.Retencion }},
+	{"papelera-purga", "CRUD_TRASH_PURGE_INTERVAL", "cada cuánto se purgan los productos vencidos de la papelera", func(c *Config) any { return &c.Papelera.Intervalo }},
+}
+
+// Cargar arma la configuración a partir de los valores por defecto, el
+// archivo de -config (o CRUD_CONFIG), el entorno y los flags de args, y
+// la valida. Con -h retorna flag.ErrHelp.
+func Cargar(args []string) (Config, error) {
+	// Los flags se leen primero para conocer -config, pero se aplican al
+	// final para que tengan la mayor precedencia
+	fs := flag.NewFlagSet("crud-api", flag.ContinueOnError)
+	ruta := fs.String("config", os.Getenv(EnvArchivo), "archivo de configuración YAML (.yaml o .yml)")
+	porDefecto := Predeterminada()
+	for _, o := range opciones {
+		registrarFlag(fs, o, o.campo(&porDefecto))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Predeterminada()
+	if *ruta != "" {
+		if err := cargarArchivo(*ruta, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	for _, o := range opciones {
+		if valor, ok := os.LookupEnv(o.env); ok {
+			if err := asignar(o.campo(&cfg), valor); err != nil {
+				errs = append(errs, fmt.Errorf("variable %s: %w", o.env, err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range opciones {
+			if o.flag == f.Name {
+				// El valor ya se validó al parsear el flag
+				asignar(o.campo(&cfg), f.Value.String())
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.Validar(); err != nil {
+		return Config{}, fmt.Errorf("configuración inválida:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// cargarArchivo aplica sobre cfg los valores presentes en el archivo YAML.
+// Las claves desconocidas son un error, para no ignorar errores de tipeo.
+func cargarArchivo(ruta string, cfg *Config) error {
+	// Solo se decodifica YAML; un .toml o .json se rechaza en lugar de
+	// leerlo como YAML y fallar con un error confuso
+	switch strings.ToLower(filepath.Ext(ruta)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("archivo de configuración %s: solo se admite YAML (extensión .yaml o .yml)", ruta)
+	}
+
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("leer configuración: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(datos))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("archivo de configuración %s: %w", ruta, err)
+	}
+	return nil
+}
+
+// registrarFlag define el flag de una opción con el valor por defecto
+// que apunta campo
+func registrarFlag(fs *flag.FlagSet, o opcion, campo any) {
+	uso := fmt.Sprintf("%s (env %s)", o.uso, o.env)
+	switch p := campo.(type) {
+	case *string:
+		fs.String(o.flag, *p, uso)
+	case *int:
+		fs.Int(o.flag, *p, uso)
+	case *float64:
+		fs.Float64(o.flag, *p, uso)
+	case *time.Duration:
+		fs.Duration(o.flag, *p, uso)
+	case *[]string:
+		lista := listaFlag(*p)
+		fs.Var(&lista, o.flag, uso)
+	default:
+		panic(fmt.Sprintf("config: tipo no soportado para -%s: %T", o.flag, campo))
+	}
+}
+
+// asignar interpreta texto según el tipo del campo y lo guarda
+func asignar(campo any, texto string) error {
+	switch p := campo.(type) {
+	case *string:
+		*p = texto
+	case *int:
+		valor, err := strconv.Atoi(texto)
+		if err != nil {
+			return fmt.Errorf("%q no es un entero", texto)
+		}
+		*p = valor
+	case *float64:
+		valor, err := strconv.ParseFloat(texto, 64)
+		if err != nil {
+			return fmt.Errorf("%q no es un número", texto)
+		}
+		*p = valor
+	case *time.Duration:
+		valor, err := time.ParseDuration(texto)
+		if err != nil {
+			return fmt.Errorf("%q no es una duración (por ejemplo 30s o 5m)", texto)
+		}
+		*p = valor
+	case *[]string:
+		*p = separarLista(texto)
+	default:
+		return fmt.Errorf("tipo no soportado: %T", campo)
+	}
+	return nil
+}
+
+// listaFlag es un flag con valores separados por coma
+type listaFlag []string
+
+func (l *listaFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listaFlag) Set(texto string) error {
+	*l = separarLista(texto)
+	return nil
+}
+
+// separarLista divide por comas y descarta los elementos vacíos
+func separarLista(texto string) []string {
+	var lista []string
+	for _, elemento := range strings.Split(texto, ",") {
+		if elemento = strings.TrimSpace(elemento); elemento != "" {
+			lista = append(lista, elemento)
+		}
+	}
+	return lista
+}
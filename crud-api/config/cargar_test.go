@@ -0,0 +1,35 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"crud-api/config"
+)
+
+func TestCargarSoloYAML(t *testing.T) {
+	dir := t.TempDir()
+	contenido := []byte("servidor:\n  direccion: \":9090\"\n")
+	for _, nombre := range []string{"config.yaml", "config.YML", "config.toml", "config.json", "config"} {
+		ruta := filepath.Join(dir, nombre)
+		if err := os.WriteFile(ruta, contenido, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := config.Cargar([]string{"-config", ruta})
+		switch strings.ToLower(filepath.Ext(nombre)) {
+		case ".yaml", ".yml":
+			if err != nil {
+				t.Errorf("%s: %v", nombre, err)
+			} else if cfg.Servidor.Direccion != ":9090" {
+				t.Errorf("%s: dirección %q, se esperaba :9090", nombre, cfg.Servidor.Direccion)
+			}
+		default:
+			if err == nil || !strings.Contains(err.Error(), "solo se admite YAML") {
+				t.Errorf("%s: error %v, se esperaba que se rechace", nombre, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package config define la configuración tipada del servidor. Se arma con
+// valores por defecto, un archivo YAML, variables de entorno y flags, en ese
+// orden de precedencia (cada fuente pisa a la anterior).
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config es la configuración completa del servidor
+type Config struct {
+	Servidor       Servidor       `yaml:"servidor"`
+	Almacenamiento Almacenamiento `yaml:"almacenamiento"`
+	Log            Log            `yaml:"log"`
+	CORS           CORS           `yaml:"cors"`
+	Auth           Auth           `yaml:"auth"`
+	Limites        Limites        `yaml:"limites"`
+	Cambio         Cambio         `yaml:"cambio"`
+	Papelera       Papelera       `yaml:"papelera"`
+}
+
+// Servidor configura el servidor HTTP
+type Servidor struct {
+	// Direccion es donde escucha, por ejemplo ":8080" o "127.0.0.1:8080"
+	Direccion string `yaml:"direccion"`
+	// Modo es el modo de Gin: debug, release o test
+	Modo     string   `yaml:"modo"`
+	TLS      TLS      `yaml:"tls"`
+	Timeouts Timeouts `yaml:"timeouts"`
+	// ProxiesConfiables son las IPs o CIDRs cuyo X-Forwarded-For se acepta
+	ProxiesConfiables []string `yaml:"proxies_confiables"`
+}
+
+// TLS son los archivos del certificado; sin ellos se sirve HTTP plano
+type TLS struct {
+	Certificado string `yaml:"certificado"`
+	Clave       string `yaml:"clave"`
+}
+
+// Habilitado reporta si se configuró TLS
+func (t TLS) Habilitado() bool {
+	return t.Certificado != "" || t.Clave != ""
+}
+
+// Timeouts del servidor HTTP; 0 es sin límite
+type Timeouts struct {
+	Lectura            time.Duration `yaml:"lectura"`
+	LecturaEncabezados time.Duration `yaml:"lectura_encabezados"`
+	Escritura          time.Duration `yaml:"escritura"`
+	Inactividad        time.Duration `yaml:"inactividad"`
+	// Apagado es cuánto se esperan las peticiones en curso al recibir
+	// SIGINT o SIGTERM antes de cortarlas
+	Apagado time.Duration `yaml:"apagado"`
+}
+
+// Almacenamiento elige y configura el backend de productos
+type Almacenamiento struct {
+	// Backend es memoria, sqlite o archivo
+	Backend string `yaml:"backend"`
+	// SQLite es la ruta de la base de datos (backend sqlite)
+	SQLite string `yaml:"sqlite"`
+	// Directorio guarda el log y el snapshot (backend archivo)
+	Directorio string `yaml:"directorio"`
+	// Compactacion es cada cuánto se compacta el log (backend archivo)
+	Compactacion time.Duration `yaml:"compactacion"`
+}
+
+// Log configura los mensajes del servidor
+type Log struct {
+	// Nivel es debug, info, warn o error
+	Nivel string `yaml:"nivel"`
+	// Formato es json (una línea por mensaje) o texto (clave=valor)
+	Formato string `yaml:"formato"`
+}
+
+// NivelSlog retorna el nivel como slog.Level; el nivel ya está validado
+func (l Log) NivelSlog() slog.Level {
+	var nivel slog.Level
+	nivel.UnmarshalText([]byte(l.Nivel))
+	return nivel
+}
+
+// Handler crea el handler de slog con el formato y nivel configurados
+func (l Log) Handler(w io.Writer) slog.Handler {
+	opciones := &slog.HandlerOptions{Level: l.NivelSlog()}
+	if l.Formato == "texto" {
+		return slog.NewTextHandler(w, opciones)
+	}
+	return slog.NewJSONHandler(w, opciones)
+}
+
+// CORS configura qué orígenes de navegador pueden usar la API
+type CORS struct {
+	// Origenes permitidos, por ejemplo "https://app.ejemplo.com"; "*"
+	// permite cualquiera y vacío desactiva CORS
+	Origenes []string `yaml:"origenes"`
+}
+
+// Auth configura los tokens y los archivos de usuarios y API keys
+type Auth struct {
+	// Algoritmo de firma de los tokens: HS256 o RS256
+	Algoritmo string `yaml:"algoritmo"`
+	// Secreto HS256, de al menos 32 bytes
+	Secreto string `yaml:"secreto"`
+	// ClavePrivada es la ruta de la clave RSA en PEM (RS256)
+	ClavePrivada string        `yaml:"clave_privada"`
+	Duracion     time.Duration `yaml:"duracion"`
+	Usuarios     string        `yaml:"usuarios"`
+	APIKeys      string        `yaml:"apikeys"`
+}
+
+// Limites configura el rate limiting de cada grupo de rutas
+type Limites struct {
+	Lectura   Limite `yaml:"lectura"`
+	Escritura Limite `yaml:"escritura"`
+	Auth      Limite `yaml:"auth"`
+}
+
+// Limite son peticiones por segundo y ráfaga máxima; Tasa 0 no limita
+type Limite struct {
+	Tasa   float64 `yaml:"tasa"`
+	Rafaga int     `yaml:"rafaga"`
+}
+
+// Cambio configura la tabla de tipos de cambio de ?currency=
+type Cambio struct {
+	// Archivo es el JSON con los tipos de cambio; vacío desactiva la
+	// conversión (solo se usan los precios fijos de cada producto)
+	Archivo string `yaml:"archivo"`
+	// Recarga es cada cuánto se revisa si el archivo cambió; 0 solo
+	// recarga con SIGHUP o POST /admin/tipos-de-cambio/recargar
+	Recarga time.Duration `yaml:"recarga"`
+}
+
+// Papelera configura la purga de los productos eliminados
+type Papelera struct {
+	// Retencion es cuánto tiempo se puede restaurar un producto eliminado
+	// antes de borrarlo definitivamente; 0 no purga nunca
+	Retencion time.Duration `yaml:"retencion"`
+	// Intervalo es cada cuánto se buscan productos vencidos
+	Intervalo time.Duration `yaml:"intervalo"`
+}
+
+// Predeterminada retorna la configuración por defecto
+func Predeterminada() Config {
+	return Config{
+		Servidor: Servidor{
+			Direccion: ":8080",
+			Modo:      "debug",
+			Timeouts: Timeouts{
+				Lectura:            15 * time.Second,
+				LecturaEncabezados: 5 * time.Second,
+				Escritura:          2 * time.Minute, // alcanza para exportar catálogos grandes
+				Inactividad:        2 * time.Minute,
+				Apagado:            30 * time.Second,
+			},
+		},
+		Almacenamiento: Almacenamiento{
+			Backend:      "memoria",
+			SQLite:       "productos.db",
+			Directorio:   "data",
+			Compactacion: 5 * time.Minute,
+		},
+		Log: Log{Nivel: "info", Formato: "json"},
+		Auth: Auth{
+			Algoritmo: "HS256",
+			Duracion:  time.Hour,
+			Usuarios:  "usuarios.json",
+			APIKeys:   "apikeys.json",
+		},
+		Limites: Limites{
+			Lectura:   Limite{Tasa: 20, Rafaga: 40},
+			Escritura: Limite{Tasa: 5, Rafaga: 10},
+			Auth:      Limite{Tasa: 0.2, Rafaga: 5},
+		},
+		Cambio:   Cambio{Recarga: time.Minute},
+		Papelera: Papelera{Retencion: 30 * 24 * time.Hour, Intervalo: time.Hour},
+	}
+}
+
+// Validar revisa toda la configuración y retorna todos los problemas
+// juntos, para corregirlos de una vez
+func (c Config) Validar() error {
+	var errs []error
+	falla := func(campo, formato string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", campo, fmt.Sprintf(formato, args...)))
+	}
+
+	if _, puerto, err := net.SplitHostPort(c.Servidor.Direccion); err != nil || puerto == "" {
+		falla("servidor.direccion", "debe tener la forma host:puerto o :puerto, no %q", c.Servidor.Direccion)
+	}
+	if !slices.Contains([]string{"debug", "release", "test"}, c.Servidor.Modo) {
+		falla("servidor.modo", "debe ser debug, release o test, no %q", c.Servidor.Modo)
+	}
+	if c.Servidor.TLS.Habilitado() {
+		if c.Servidor.TLS.Certificado == "" || c.Servidor.TLS.Clave == "" {
+			falla("servidor.tls", "hacen falta el certificado y la clave")
+		}
+		for _, ruta := range []string{c.Servidor.TLS.Certificado, c.Servidor.TLS.Clave} {
+			if _, err := os.Stat(ruta); ruta != "" && err != nil {
+				falla("servidor.tls", "%v", err)
+			}
+		}
+	}
+	timeouts := []struct {
+		campo string
+		valor time.Duration
+	}{
+		{"servidor.timeouts.lectura", c.Servidor.Timeouts.Lectura},
+		{"servidor.timeouts.lectura_encabezados", c.Servidor.Timeouts.LecturaEncabezados},
+		{"servidor.timeouts.escritura", c.Servidor.Timeouts.Escritura},
+		{"servidor.timeouts.inactividad", c.Servidor.Timeouts.Inactividad},
+	}
+	for _, t := range timeouts {
+		if t.valor < 0 {
+			falla(t.campo, "no puede ser negativo")
+		}
+	}
+	if c.Servidor.Timeouts.Apagado <= 0 {
+		falla("servidor.timeouts.apagado", "debe ser positivo")
+	}
+	for _, proxy := range c.Servidor.ProxiesConfiables {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				falla("servidor.proxies_confiables", "%q no es una IP ni un CIDR", proxy)
+			}
+		}
+	}
+
+	switch c.Almacenamiento.Backend {
+	case "memoria":
+	case "sqlite":
+		if c.Almacenamiento.SQLite == "" {
+			falla("almacenamiento.sqlite", "es obligatorio con el backend sqlite")
+		}
+	case "archivo":
+		if c.Almacenamiento.Directorio == "" {
+			falla("almacenamiento.directorio", "es obligatorio con el backend archivo")
+		}
+		if c.Almacenamiento.Compactacion <= 0 {
+			falla("almacenamiento.compactacion", "debe ser positivo")
+		}
+	default:
+		falla("almacenamiento.backend", "debe ser memoria, sqlite o archivo, no %q", c.Almacenamiento.Backend)
+	}
+
+	var nivel slog.Level
+	if err := nivel.UnmarshalText([]byte(c.Log.Nivel)); err != nil {
+		falla("log.nivel", "debe ser debug, info, warn o error, no %q", c.Log.Nivel)
+	}
+	if !slices.Contains([]string{"json", "texto"}, c.Log.Formato) {
+		falla("log.formato", "debe ser json o texto, no %q", c.Log.Formato)
+	}
+
+	for _, origen := range c.CORS.Origenes {
+		if origen == "*" {
+			continue
+		}
+		u, err := url.Parse(origen)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			falla("cors.origenes", "%q debe ser \"*\" o de la forma https://host[:puerto]", origen)
+		}
+	}
+
+	if !slices.Contains([]string{"HS256", "RS256"}, c.Auth.Algoritmo) {
+		falla("auth.algoritmo", "debe ser HS256 o RS256, no %q", c.Auth.Algoritmo)
+	}
+	if c.Auth.Secreto != "" && len(c.Auth.Secreto) < 32 {
+		falla("auth.secreto", "debe tener al menos 32 bytes")
+	}
+	if c.Auth.Algoritmo == "RS256" && c.Auth.ClavePrivada == "" {
+		falla("auth.clave_privada", "es obligatoria con RS256")
+	}
+	if c.Auth.Duracion <= 0 {
+		falla("auth.duracion", "debe ser positiva")
+	}
+
+	limites := []struct {
+		campo  string
+		limite Limite
+	}{
+		{"limites.lectura", c.Limites.Lectura},
+		{"limites.escritura", c.Limites.Escritura},
+		{"limites.auth", c.Limites.Auth},
+	}
+	for _, l := range limites {
+		if l.limite.Tasa < 0 {
+			falla(l.campo+".tasa", "no puede ser negativa")
+		}
+		if l.limite.Tasa > 0 && l.limite.Rafaga < 1 {
+			falla(l.campo+".rafaga", "debe ser al menos 1")
+		}
+	}
+
+	if c.Cambio.Recarga < 0 {
+		falla("cambio.recarga", "no puede ser negativa")
+	}
+
+	if c.Papelera.Retencion < 0 {
+		falla("papelera.retencion", "no puede ser negativa")
+	}
+	if c.Papelera.Retencion > 0 && c.Papelera.Intervalo <= 0 {
+		falla("papelera.intervalo", "debe ser positivo si hay retención")
+	}
+
+	return errors.Join(errs...)
+}
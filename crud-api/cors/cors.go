@@ -0,0 +1,57 @@
+// Package cors permite que aplicaciones web de otros orígenes usen la API
+// desde el navegador.
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// metodosPermitidos son los métodos que usa la API
+	metodosPermitidos = strings.Join([]string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}, ", ")
+	// headersPermitidos son los que el navegador puede enviar
+	headersPermitidos = "Authorization, Content-Type, If-Match, If-None-Match, X-API-Key, X-Request-ID"
+	// headersExpuestos son los que el JavaScript del cliente puede leer
+	headersExpuestos = "ETag, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-Request-ID"
+)
+
+// Middleware agrega los headers CORS para los orígenes permitidos y
+// responde las peticiones preflight. "*" permite cualquier origen. Sin
+// orígenes no hace nada.
+func Middleware(origenes []string) gin.HandlerFunc {
+	todos := slices.Contains(origenes, "*")
+
+	return func(c *gin.Context) {
+		origen := c.GetHeader("Origin")
+		if len(origenes) == 0 || origen == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		if !todos && !slices.Contains(origenes, origen) {
+			// Sin headers CORS el navegador bloquea la respuesta
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origen)
+		c.Header("Access-Control-Expose-Headers", headersExpuestos)
+
+		// Preflight: el navegador pregunta antes de la petición real
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", metodosPermitidos)
+			c.Header("Access-Control-Allow-Headers", headersPermitidos)
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
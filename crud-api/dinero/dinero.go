@@ -0,0 +1,304 @@
+// Package dinero representa importes exactos: una cantidad entera de
+// unidades menores (centavos, por ejemplo) y el código ISO 4217 de la
+// moneda. Evita los errores de redondeo que acumula float64 al sumar o
+// multiplicar precios.
+package dinero
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Errores de las operaciones con importes. Los que describen un valor
+// inválido están redactados para ir después del nombre del campo.
+var (
+	ErrFormato           = errors.New("no es un importe decimal válido (por ejemplo 19.99)")
+	ErrNoEsTexto         = errors.New(`debe ser un texto decimal (por ejemplo "19.99"), no un número`)
+	ErrPrecision         = errors.New("tiene más decimales de los que admite la moneda")
+	ErrMonedaDesconocida = errors.New("no es un código de moneda ISO 4217 conocido")
+	ErrDesborde          = errors.New("excede el importe máximo representable")
+	ErrMonedasDistintas  = errors.New("no se pueden operar importes de monedas distintas")
+	ErrDivisionPorCero   = errors.New("no se puede dividir en cero partes")
+)
+
+// Dinero es un importe en una moneda. El valor cero no tiene moneda; los
+// importes se crean con Nuevo, Parsear o DesdeRat.
+type Dinero struct {
+	unidades int64
+	moneda   string
+}
+
+// Nuevo crea un importe a partir de unidades menores, por ejemplo
+// Nuevo(1999, "USD") son 19.99 USD
+func Nuevo(unidades int64, moneda string) (Dinero, error) {
+	if _, ok := Decimales(moneda); !ok {
+		return Dinero{}, ErrMonedaDesconocida
+	}
+	return Dinero{unidades: unidades, moneda: moneda}, nil
+}
+
+// Parsear lee un importe decimal como "19.99" o "-3". Es estricto: no
+// admite signo +, exponentes, separadores de miles, espacios ni más
+// decimales de los que tiene la moneda ("19.999" en USD es un error, no
+// se redondea).
+func Parsear(texto, moneda string) (Dinero, error) {
+	cantidad, ok := Decimales(moneda)
+	if !ok {
+		return Dinero{}, ErrMonedaDesconocida
+	}
+
+	negativo := strings.HasPrefix(texto, "-")
+	texto = strings.TrimPrefix(texto, "-")
+	entera, fraccion, conPunto := strings.Cut(texto, ".")
+	if !soloDigitos(entera) || (conPunto && !soloDigitos(fraccion)) {
+		return Dinero{}, ErrFormato
+	}
+	if len(fraccion) > cantidad {
+		return Dinero{}, fmt.Errorf("%w (%s admite %d)", ErrPrecision, moneda, cantidad)
+	}
+
+	digitos := entera + fraccion + strings.Repeat("0", cantidad-len(fraccion))
+	valor, ok := new(big.Int).SetString(digitos, 10)
+	if !ok {
+		return Dinero{}, ErrFormato
+	}
+	if negativo {
+		valor.Neg(valor)
+	}
+	if !valor.IsInt64() {
+		return Dinero{}, ErrDesborde
+	}
+	return Dinero{unidades: valor.Int64(), moneda: moneda}, nil
+}
+
+// soloDigitos indica si texto tiene al menos un dígito y nada más
+func soloDigitos(texto string) bool {
+	if texto == "" {
+		return false
+	}
+	for _, r := range texto {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DesdeRat convierte un valor exacto (en unidades de la moneda, no en
+// unidades menores) al importe más cercano según el modo de redondeo
+func DesdeRat(valor *big.Rat, moneda string, modo Redondeo) (Dinero, error) {
+	cantidad, ok := Decimales(moneda)
+	if !ok {
+		return Dinero{}, ErrMonedaDesconocida
+	}
+	escalado := new(big.Rat).Mul(valor, new(big.Rat).SetInt64(potencias[cantidad]))
+	unidades, err := redondear(escalado, modo)
+	if err != nil {
+		return Dinero{}, err
+	}
+	return Dinero{unidades: unidades, moneda: moneda}, nil
+}
+
+// Unidades retorna el importe en unidades menores
+func (d Dinero) Unidades() int64 {
+	return d.unidades
+}
+
+// Moneda retorna el código ISO 4217 del importe
+func (d Dinero) Moneda() string {
+	return d.moneda
+}
+
+// EsCero indica si el importe es cero
+func (d Dinero) EsCero() bool {
+	return d.unidades == 0
+}
+
+// Signo retorna -1, 0 o 1 según el importe sea negativo, cero o positivo
+func (d Dinero) Signo() int {
+	switch {
+	case d.unidades < 0:
+		return -1
+	case d.unidades > 0:
+		return 1
+	}
+	return 0
+}
+
+// Rat retorna el importe exacto en unidades de la moneda
+func (d Dinero) Rat() *big.Rat {
+	cantidad, _ := Decimales(d.moneda)
+	return big.NewRat(d.unidades, potencias[cantidad])
+}
+
+// Decimal retorna el importe con todos los decimales de la moneda, por
+// ejemplo "899.90" o "1500" en JPY
+func (d Dinero) Decimal() string {
+	cantidad, _ := Decimales(d.moneda)
+	texto := strconv.FormatUint(absoluto(d.unidades), 10)
+	if cantidad > 0 {
+		if len(texto) <= cantidad {
+			texto = strings.Repeat("0", cantidad-len(texto)+1) + texto
+		}
+		texto = texto[:len(texto)-cantidad] + "." + texto[len(texto)-cantidad:]
+	}
+	if d.unidades < 0 {
+		texto = "-" + texto
+	}
+	return texto
+}
+
+// String retorna el importe seguido de la moneda, por ejemplo "899.90 USD"
+func (d Dinero) String() string {
+	if d.moneda == "" {
+		return d.Decimal()
+	}
+	return d.Decimal() + " " + d.moneda
+}
+
+// absoluto retorna |n| sin desbordar con math.MinInt64
+func absoluto(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// mismaMoneda falla si los importes son de monedas distintas. El cero sin
+// moneda se puede operar con cualquiera, así un acumulador Dinero{} sirve
+// para sumar.
+func (d Dinero) mismaMoneda(otro Dinero) (string, error) {
+	switch {
+	case d.moneda == otro.moneda, otro.moneda == "":
+		return d.moneda, nil
+	case d.moneda == "":
+		return otro.moneda, nil
+	}
+	return "", fmt.Errorf("%w: %s y %s", ErrMonedasDistintas, d.moneda, otro.moneda)
+}
+
+// Sumar retorna d + otro
+func (d Dinero) Sumar(otro Dinero) (Dinero, error) {
+	moneda, err := d.mismaMoneda(otro)
+	if err != nil {
+		return Dinero{}, err
+	}
+	suma := d.unidades + otro.unidades
+	if (otro.unidades > 0 && suma < d.unidades) || (otro.unidades < 0 && suma > d.unidades) {
+		return Dinero{}, ErrDesborde
+	}
+	return Dinero{unidades: suma, moneda: moneda}, nil
+}
+
+// Restar retorna d - otro
+func (d Dinero) Restar(otro Dinero) (Dinero, error) {
+	if otro.unidades == math.MinInt64 {
+		return Dinero{}, ErrDesborde
+	}
+	return d.Sumar(Dinero{unidades: -otro.unidades, moneda: otro.moneda})
+}
+
+// Multiplicar retorna d * n, por ejemplo el total de n unidades
+func (d Dinero) Multiplicar(n int64) (Dinero, error) {
+	return d.Escalar(new(big.Rat).SetInt64(n), MitadPar)
+}
+
+// Escalar multiplica el importe por un factor exacto (un descuento, un
+// impuesto o un tipo de cambio) y redondea a unidades menores
+func (d Dinero) Escalar(factor *big.Rat, modo Redondeo) (Dinero, error) {
+	producto := new(big.Rat).Mul(new(big.Rat).SetInt64(d.unidades), factor)
+	unidades, err := redondear(producto, modo)
+	if err != nil {
+		return Dinero{}, err
+	}
+	return Dinero{unidades: unidades, moneda: d.moneda}, nil
+}
+
+// Dividir retorna d / n redondeado. Para repartir un importe sin perder
+// centavos conviene Repartir.
+func (d Dinero) Dividir(n int64, modo Redondeo) (Dinero, error) {
+	if n == 0 {
+		return Dinero{}, ErrDivisionPorCero
+	}
+	return d.Escalar(big.NewRat(1, n), modo)
+}
+
+// Repartir divide el importe en partes que difieren en a lo sumo una
+// unidad menor y suman exactamente d; las primeras reciben el resto
+func (d Dinero) Repartir(partes int) ([]Dinero, error) {
+	if partes <= 0 {
+		return nil, ErrDivisionPorCero
+	}
+	cociente, resto := d.unidades/int64(partes), d.unidades%int64(partes)
+	unidad := int64(1)
+	if resto < 0 {
+		unidad, resto = -1, -resto
+	}
+
+	resultado := make([]Dinero, partes)
+	for i := range resultado {
+		resultado[i] = Dinero{unidades: cociente, moneda: d.moneda}
+		if int64(i) < resto {
+			resultado[i].unidades += unidad
+		}
+	}
+	return resultado, nil
+}
+
+// Comparar retorna -1, 0 o 1 según d sea menor, igual o mayor que otro;
+// falla si son de monedas distintas
+func (d Dinero) Comparar(otro Dinero) (int, error) {
+	if _, err := d.mismaMoneda(otro); err != nil {
+		return 0, err
+	}
+	switch {
+	case d.unidades < otro.unidades:
+		return -1, nil
+	case d.unidades > otro.unidades:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// CompararValor compara el valor numérico de dos importes sin importar la
+// moneda (1 JPY es mayor que 0.50 USD). Sirve para ordenar listados, no
+// para comparar precios reales en monedas distintas.
+func CompararValor(a, b Dinero) int {
+	if a.moneda == b.moneda {
+		c, _ := a.Comparar(b)
+		return c
+	}
+	return a.Rat().Cmp(b.Rat())
+}
+
+// MarshalJSON escribe el importe como texto decimal ("19.99"), para que
+// ningún cliente lo lea como float
+func (d Dinero) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Decimal())
+}
+
+// UnmarshalJSON lee un texto decimal con las reglas de Parsear; un número
+// JSON se rechaza, porque el cliente que lo envía ya lo pasó por un float.
+// La moneda debe estar asignada antes, porque define cuántos decimales se
+// admiten.
+func (d *Dinero) UnmarshalJSON(datos []byte) error {
+	if bytes.Equal(datos, []byte("null")) {
+		return nil
+	}
+	var texto string
+	if !bytes.HasPrefix(datos, []byte(`"`)) || json.Unmarshal(datos, &texto) != nil {
+		return ErrNoEsTexto
+	}
+	importe, err := Parsear(texto, d.moneda)
+	if err != nil {
+		return err
+	}
+	*d = importe
+	return nil
+}
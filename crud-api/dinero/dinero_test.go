@@ -0,0 +1,293 @@
+package dinero_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"crud-api/dinero"
+)
+
+func TestParsear(t *testing.T) {
+	casos := []struct {
+		texto, moneda string
+		unidades      int64
+		err           error
+	}{
+		{"19.99", "USD", 1999, nil},
+		{"19.9", "USD", 1990, nil},
+		{"19", "USD", 1900, nil},
+		{"-3", "USD", -300, nil},
+		{"0.05", "USD", 5, nil},
+		{"19.999", "USD", 0, dinero.ErrPrecision},
+		{"1500", "JPY", 1500, nil},
+		{"1500.0", "JPY", 0, dinero.ErrPrecision},
+		{"2.5", "KWD", 2500, nil},
+		{"2.505", "KWD", 2505, nil},
+		{"2.5055", "KWD", 0, dinero.ErrPrecision},
+		{"1.2345", "CLF", 12345, nil},
+		{"1.23456", "CLF", 0, dinero.ErrPrecision},
+		{"+1", "USD", 0, dinero.ErrFormato},
+		{"1e2", "USD", 0, dinero.ErrFormato},
+		{"1,000", "USD", 0, dinero.ErrFormato},
+		{" 1", "USD", 0, dinero.ErrFormato},
+		{"", "USD", 0, dinero.ErrFormato},
+		{".5", "USD", 0, dinero.ErrFormato},
+		{"1.", "USD", 0, dinero.ErrFormato},
+		{"-", "USD", 0, dinero.ErrFormato},
+		{"92233720368547758.07", "USD", math.MaxInt64, nil},
+		{"92233720368547758.08", "USD", 0, dinero.ErrDesborde},
+		{"1", "XYZ", 0, dinero.ErrMonedaDesconocida},
+		{"1", "", 0, dinero.ErrMonedaDesconocida},
+	}
+	for _, caso := range casos {
+		importe, err := dinero.Parsear(caso.texto, caso.moneda)
+		if !errors.Is(err, caso.err) {
+			t.Errorf("Parsear(%q, %s): error %v, se esperaba %v", caso.texto, caso.moneda, err, caso.err)
+			continue
+		}
+		if err == nil && (importe.Unidades() != caso.unidades || importe.Moneda() != caso.moneda) {
+			t.Errorf("Parsear(%q, %s) = %d %s, se esperaba %d", caso.texto, caso.moneda, importe.Unidades(), importe.Moneda(), caso.unidades)
+		}
+	}
+}
+
+// modos en el orden de las columnas de las tablas de redondeo
+var modos = [...]dinero.Redondeo{dinero.MitadPar, dinero.MitadArriba, dinero.HaciaCero, dinero.AlejandoDeCero, dinero.HaciaAbajo, dinero.HaciaArriba}
+
+func TestDesdeRat(t *testing.T) {
+	casos := []struct {
+		valor string
+		// esperado son las unidades en USD para cada modo, en el orden de
+		// modos
+		esperado [len(modos)]int64
+	}{
+		{"0.12", [...]int64{12, 12, 12, 12, 12, 12}},
+		{"0.125", [...]int64{12, 13, 12, 13, 12, 13}},
+		{"0.135", [...]int64{14, 14, 13, 14, 13, 14}},
+		{"0.126", [...]int64{13, 13, 12, 13, 12, 13}},
+		{"0.124", [...]int64{12, 12, 12, 13, 12, 13}},
+		{"-0.12", [...]int64{-12, -12, -12, -12, -12, -12}},
+		{"-0.125", [...]int64{-12, -13, -12, -13, -13, -12}},
+		{"-0.135", [...]int64{-14, -14, -13, -14, -14, -13}},
+		{"-0.126", [...]int64{-13, -13, -12, -13, -13, -12}},
+		{"-0.124", [...]int64{-12, -12, -12, -13, -13, -12}},
+		{"-0.005", [...]int64{0, -1, 0, -1, -1, 0}},
+		{"1/3", [...]int64{33, 33, 33, 34, 33, 34}},
+		{"-2/3", [...]int64{-67, -67, -66, -67, -67, -66}},
+	}
+	for _, caso := range casos {
+		valor, ok := new(big.Rat).SetString(caso.valor)
+		if !ok {
+			t.Fatalf("valor inválido %q", caso.valor)
+		}
+		for i, modo := range modos {
+			importe, err := dinero.DesdeRat(valor, "USD", modo)
+			if err != nil {
+				t.Fatalf("DesdeRat(%s, modo %d): %v", caso.valor, modo, err)
+			}
+			if importe.Unidades() != caso.esperado[i] {
+				t.Errorf("DesdeRat(%s, modo %d) = %d, se esperaba %d", caso.valor, modo, importe.Unidades(), caso.esperado[i])
+			}
+		}
+	}
+
+	// Las unidades menores dependen de la moneda
+	valor := big.NewRat(5, 2)
+	for moneda, esperado := range map[string]int64{"JPY": 2, "USD": 250, "KWD": 2500} {
+		importe, err := dinero.DesdeRat(valor, moneda, dinero.MitadPar)
+		if err != nil || importe.Unidades() != esperado {
+			t.Errorf("DesdeRat(5/2, %s) = %d, %v; se esperaba %d", moneda, importe.Unidades(), err, esperado)
+		}
+	}
+	if _, err := dinero.DesdeRat(big.NewRat(math.MaxInt64, 1), "USD", dinero.MitadPar); !errors.Is(err, dinero.ErrDesborde) {
+		t.Errorf("DesdeRat fuera de rango: %v, se esperaba ErrDesborde", err)
+	}
+}
+
+func TestEscalar(t *testing.T) {
+	casos := []struct {
+		unidades int64
+		factor   string
+		esperado [len(modos)]int64
+	}{
+		// Un 15 % de 19.99 son 2.9985
+		{1999, "0.15", [...]int64{300, 300, 299, 300, 299, 300}},
+		{25, "1/2", [...]int64{12, 13, 12, 13, 12, 13}},
+		{-25, "1/2", [...]int64{-12, -13, -12, -13, -13, -12}},
+		{35, "-1/2", [...]int64{-18, -18, -17, -18, -18, -17}},
+		{1000, "1.1", [...]int64{1100, 1100, 1100, 1100, 1100, 1100}},
+	}
+	for _, caso := range casos {
+		factor, ok := new(big.Rat).SetString(caso.factor)
+		if !ok {
+			t.Fatalf("factor inválido %q", caso.factor)
+		}
+		importe, err := dinero.Nuevo(caso.unidades, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, modo := range modos {
+			resultado, err := importe.Escalar(factor, modo)
+			if err != nil {
+				t.Fatalf("%d × %s (modo %d): %v", caso.unidades, caso.factor, modo, err)
+			}
+			if resultado.Unidades() != caso.esperado[i] || resultado.Moneda() != "USD" {
+				t.Errorf("%d × %s (modo %d) = %s, se esperaban %d unidades", caso.unidades, caso.factor, modo, resultado, caso.esperado[i])
+			}
+		}
+	}
+
+	grande, _ := dinero.Nuevo(math.MaxInt64, "USD")
+	if _, err := grande.Multiplicar(2); !errors.Is(err, dinero.ErrDesborde) {
+		t.Errorf("Multiplicar fuera de rango: %v, se esperaba ErrDesborde", err)
+	}
+	if _, err := grande.Dividir(0, dinero.MitadPar); !errors.Is(err, dinero.ErrDivisionPorCero) {
+		t.Errorf("Dividir en 0: %v, se esperaba ErrDivisionPorCero", err)
+	}
+}
+
+func TestRepartir(t *testing.T) {
+	casos := []struct {
+		unidades int64
+		partes   int
+		esperado []int64
+	}{
+		{100, 3, []int64{34, 33, 33}},
+		{-100, 3, []int64{-34, -33, -33}},
+		{1, 4, []int64{1, 0, 0, 0}},
+		{-1, 4, []int64{-1, 0, 0, 0}},
+		{0, 2, []int64{0, 0}},
+		{7, 7, []int64{1, 1, 1, 1, 1, 1, 1}},
+		{1999, 1, []int64{1999}},
+		{1001, 6, []int64{167, 167, 167, 167, 167, 166}},
+	}
+	for _, caso := range casos {
+		importe, err := dinero.Nuevo(caso.unidades, "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		partes, err := importe.Repartir(caso.partes)
+		if err != nil {
+			t.Fatalf("Repartir(%d, %d): %v", caso.unidades, caso.partes, err)
+		}
+		if len(partes) != len(caso.esperado) {
+			t.Fatalf("Repartir(%d, %d): %d partes", caso.unidades, caso.partes, len(partes))
+		}
+
+		var suma dinero.Dinero
+		for i, parte := range partes {
+			if parte.Unidades() != caso.esperado[i] || parte.Moneda() != "EUR" {
+				t.Errorf("Repartir(%d, %d)[%d] = %s, se esperaban %d unidades", caso.unidades, caso.partes, i, parte, caso.esperado[i])
+			}
+			if suma, err = suma.Sumar(parte); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if suma.Unidades() != importe.Unidades() {
+			t.Errorf("Repartir(%d, %d): las partes suman %d", caso.unidades, caso.partes, suma.Unidades())
+		}
+	}
+
+	// Las partes también suman el original en los extremos del rango
+	for _, unidades := range []int64{math.MaxInt64, math.MinInt64} {
+		importe, _ := dinero.Nuevo(unidades, "USD")
+		partes, err := importe.Repartir(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suma := new(big.Int)
+		for _, parte := range partes {
+			suma.Add(suma, big.NewInt(parte.Unidades()))
+		}
+		if !suma.IsInt64() || suma.Int64() != unidades {
+			t.Errorf("Repartir(%d, 7): las partes suman %s", unidades, suma)
+		}
+	}
+
+	importe, _ := dinero.Nuevo(100, "USD")
+	for _, partes := range []int{0, -1} {
+		if _, err := importe.Repartir(partes); !errors.Is(err, dinero.ErrDivisionPorCero) {
+			t.Errorf("Repartir(%d): %v, se esperaba ErrDivisionPorCero", partes, err)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	casos := []struct {
+		unidades int64
+		moneda   string
+		json     string
+	}{
+		{1999, "USD", `"19.99"`},
+		{5, "USD", `"0.05"`},
+		{-5, "USD", `"-0.05"`},
+		{0, "USD", `"0.00"`},
+		{1500, "JPY", `"1500"`},
+		{2500, "KWD", `"2.500"`},
+		{12345, "CLF", `"1.2345"`},
+		{math.MinInt64, "USD", `"-92233720368547758.08"`},
+	}
+	for _, caso := range casos {
+		importe, err := dinero.Nuevo(caso.unidades, caso.moneda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		datos, err := json.Marshal(importe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(datos) != caso.json {
+			t.Errorf("Marshal(%d %s) = %s, se esperaba %s", caso.unidades, caso.moneda, datos, caso.json)
+		}
+
+		// Al leer, la moneda ya está asignada y define los decimales
+		leido, _ := dinero.Nuevo(0, caso.moneda)
+		if err := json.Unmarshal(datos, &leido); err != nil {
+			t.Errorf("Unmarshal(%s, %s): %v", datos, caso.moneda, err)
+		} else if leido != importe {
+			t.Errorf("Unmarshal(%s, %s) = %s, se esperaba %s", datos, caso.moneda, leido, importe)
+		}
+	}
+}
+
+func TestJSONRechazaNumeros(t *testing.T) {
+	casos := []struct {
+		json string
+		err  error
+	}{
+		{`19.99`, dinero.ErrNoEsTexto},
+		{`1999`, dinero.ErrNoEsTexto},
+		{`-1`, dinero.ErrNoEsTexto},
+		{`1e3`, dinero.ErrNoEsTexto},
+		{`true`, dinero.ErrNoEsTexto},
+		{`{}`, dinero.ErrNoEsTexto},
+		{`["19.99"]`, dinero.ErrNoEsTexto},
+		{`"19.999"`, dinero.ErrPrecision},
+		{`"1e3"`, dinero.ErrFormato},
+		{`""`, dinero.ErrFormato},
+	}
+	for _, caso := range casos {
+		importe, _ := dinero.Nuevo(0, "USD")
+		if err := importe.UnmarshalJSON([]byte(caso.json)); !errors.Is(err, caso.err) {
+			t.Errorf("UnmarshalJSON(%s): %v, se esperaba %v", caso.json, err, caso.err)
+		}
+	}
+
+	// Dentro de un objeto el error llega igual al que llama a json.Unmarshal
+	var destino struct {
+		Precio dinero.Dinero `json:"precio"`
+	}
+	destino.Precio, _ = dinero.Nuevo(0, "USD")
+	if err := json.Unmarshal([]byte(`{"precio": 19.99}`), &destino); !errors.Is(err, dinero.ErrNoEsTexto) {
+		t.Errorf("precio numérico en un objeto: %v, se esperaba ErrNoEsTexto", err)
+	}
+
+	// null no modifica el importe
+	importe, _ := dinero.Nuevo(1999, "USD")
+	if err := json.Unmarshal([]byte(`null`), &importe); err != nil || importe.Unidades() != 1999 {
+		t.Errorf("null: %s, %v", importe, err)
+	}
+}
@@ -0,0 +1,38 @@
+package dinero
+
+import "strings"
+
+// decimales es la cantidad de unidades menores (decimales) de cada moneda
+// de la norma ISO 4217. Las que no figuran se rechazan.
+var decimales = func() map[string]int {
+	porDecimales := map[int]string{
+		0: "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF",
+		2: "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD " +
+			"BTN BWP BYN BZD CAD CDF CHE CHF CHW CNY COP COU CRC CUP CVE CZK DKK DOP DZD EGP " +
+			"ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR IRR " +
+			"JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD MMK MNT MOP MRU " +
+			"MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR " +
+			"RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS " +
+			"TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED VES WST XCD YER ZAR ZMW ZWL",
+		3: "BHD IQD JOD KWD LYD OMR TND",
+		4: "CLF UYW",
+	}
+
+	tabla := make(map[string]int)
+	for cantidad, codigos := range porDecimales {
+		for _, codigo := range strings.Fields(codigos) {
+			tabla[codigo] = cantidad
+		}
+	}
+	return tabla
+}()
+
+// Decimales retorna cuántos decimales admite la moneda y si es un código
+// ISO 4217 conocido
+func Decimales(moneda string) (int, bool) {
+	cantidad, ok := decimales[moneda]
+	return cantidad, ok
+}
+
+// potencias son las potencias de 10 hasta la mayor cantidad de decimales
+var potencias = [...]int64{1, 10, 100, 1000, 10000}
@@ -0,0 +1,61 @@
+package dinero
+
+import "math/big"
+
+// Redondeo indica cómo llevar un resultado exacto a unidades menores
+type Redondeo int
+
+const (
+	// MitadPar redondea al más cercano y los empates al par (redondeo
+	// bancario); es el modo por defecto porque no sesga las sumas
+	MitadPar Redondeo = iota
+	// MitadArriba redondea al más cercano y los empates alejándose de cero
+	MitadArriba
+	// HaciaCero descarta la fracción (truncar)
+	HaciaCero
+	// AlejandoDeCero sube cualquier fracción en valor absoluto
+	AlejandoDeCero
+	// HaciaAbajo redondea hacia menos infinito (piso)
+	HaciaAbajo
+	// HaciaArriba redondea hacia más infinito (techo)
+	HaciaArriba
+)
+
+// redondear lleva un racional al entero que corresponde según el modo
+func redondear(valor *big.Rat, modo Redondeo) (int64, error) {
+	cociente, resto := new(big.Int).QuoRem(valor.Num(), valor.Denom(), new(big.Int))
+
+	if resto.Sign() != 0 {
+		// El cociente está truncado hacia cero; signo indica hacia dónde
+		// queda la fracción descartada
+		signo := int64(valor.Sign())
+
+		// Comparar la fracción con 1/2: 2·|resto| contra el denominador
+		mitad := new(big.Int).Abs(resto)
+		mitad.Lsh(mitad, 1)
+		frente := mitad.Cmp(valor.Denom())
+
+		alejar := false
+		switch modo {
+		case MitadPar:
+			alejar = frente > 0 || (frente == 0 && cociente.Bit(0) == 1)
+		case MitadArriba:
+			alejar = frente >= 0
+		case HaciaCero:
+		case AlejandoDeCero:
+			alejar = true
+		case HaciaAbajo:
+			alejar = signo < 0
+		case HaciaArriba:
+			alejar = signo > 0
+		}
+		if alejar {
+			cociente.Add(cociente, big.NewInt(signo))
+		}
+	}
+
+	if !cociente.IsInt64() {
+		return 0, ErrDesborde
+	}
+	return cociente.Int64(), nil
+}
@@ -0,0 +1,161 @@
+// Package errores define el modelo único de respuestas de error de la API
+// (RFC 7807, application/problem+json) y el middleware que lo aplica.
+//
+// Los handlers no escriben errores directamente: llaman a c.Error con un
+// *Error y el middleware arma la respuesta al final de la petición.
+package errores
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ContentType es el tipo de las respuestas de error
+const ContentType = "application/problem+json"
+
+// prefijoTipo arma el campo "type" a partir del código del problema
+const prefijoTipo = "urn:crud-api:problema:"
+
+// Códigos de error legibles por máquina
+const (
+	CodigoIDInvalido            = "id_invalido"
+	CodigoJSONInvalido          = "json_invalido"
+	CodigoValidacion            = "validacion"
+	CodigoParametroInvalido     = "parametro_invalido"
+	CodigoNoEncontrado          = "no_encontrado"
+	CodigoNoAutenticado         = "no_autenticado"
+	CodigoCredencialesInvalidas = "credenciales_invalidas"
+	CodigoSinPermiso            = "sin_permiso"
+	CodigoRutaNoEncontrada      = "ruta_no_encontrada"
+	CodigoMetodoNoPermitido     = "metodo_no_permitido"
+	CodigoVersionNoCoincide     = "version_no_coincide"
+	CodigoConflicto             = "conflicto"
+	CodigoSKUDuplicado          = "sku_duplicado"
+	CodigoProductoNoEliminado   = "producto_no_eliminado"
+	CodigoCategoriaDuplicada    = "categoria_duplicada"
+	CodigoCicloCategorias       = "ciclo_de_categorias"
+	CodigoCategoriaEnUso        = "categoria_en_uso"
+	CodigoSinTipoDeCambio       = "sin_tipo_de_cambio"
+	CodigoTiposDeCambio         = "tipos_de_cambio_invalidos"
+	CodigoPatchInvalido         = "patch_invalido"
+	CodigoLoteCancelado         = "lote_cancelado"
+	CodigoTipoNoSoportado       = "tipo_contenido_no_soportado"
+	CodigoCuerpoDemasiadoGrande = "cuerpo_demasiado_grande"
+	CodigoDemasiadasPeticiones  = "demasiadas_peticiones"
+	CodigoNoDisponible          = "servicio_no_disponible"
+	CodigoInterno               = "error_interno"
+)
+
+// Campo describe por qué un campo (o una fila, al importar) no es válido
+type Campo struct {
+	Linea   int    `json:"linea,omitempty"`
+	Campo   string `json:"campo,omitempty"`
+	Mensaje string `json:"mensaje"`
+}
+
+// Error es un error de la API con todo lo necesario para responderlo
+type Error struct {
+	Status  int
+	Codigo  string
+	Titulo  string
+	Detalle string
+	Campos  []Campo
+	// Extra agrega miembros propios del problema a la respuesta
+	Extra map[string]any
+	// Causa es el error original, solo para logs
+	Causa error
+}
+
+// Nuevo crea un error con su código HTTP, código de máquina y mensaje
+func Nuevo(status int, codigo, titulo string) *Error {
+	return &Error{Status: status, Codigo: codigo, Titulo: titulo}
+}
+
+// Interno envuelve un error inesperado; al cliente solo le llega un
+// mensaje genérico
+func Interno(causa error) *Error {
+	e := Nuevo(http.StatusInternalServerError, CodigoInterno, "Error interno del servidor")
+	e.Causa = causa
+	return e
+}
+
+// ConDetalle agrega una explicación específica de esta ocurrencia
+func (e *Error) ConDetalle(formato string, args ...any) *Error {
+	e.Detalle = fmt.Sprintf(formato, args...)
+	return e
+}
+
+// ConExtra agrega un miembro adicional a la respuesta
+func (e *Error) ConExtra(clave string, valor any) *Error {
+	if e.Extra == nil {
+		e.Extra = map[string]any{}
+	}
+	e.Extra[clave] = valor
+	return e
+}
+
+func (e *Error) Error() string {
+	mensaje := e.Codigo + ": " + e.Titulo
+	if e.Detalle != "" {
+		mensaje += " (" + e.Detalle + ")"
+	}
+	if e.Causa != nil {
+		mensaje += ": " + e.Causa.Error()
+	}
+	return mensaje
+}
+
+func (e *Error) Unwrap() error {
+	return e.Causa
+}
+
+// Problema retorna el cuerpo problem+json para la instancia indicada
+func (e *Error) Problema(instancia string) Problema {
+	return Problema{
+		Tipo:      prefijoTipo + e.Codigo,
+		Titulo:    e.Titulo,
+		Status:    e.Status,
+		Detalle:   e.Detalle,
+		Instancia: instancia,
+		Codigo:    e.Codigo,
+		Errores:   e.Campos,
+		Extra:     e.Extra,
+	}
+}
+
+// Problema es el cuerpo de una respuesta de error según la RFC 7807.
+// Codigo y Errores son miembros de extensión propios de esta API.
+type Problema struct {
+	Tipo      string  `json:"type"`
+	Titulo    string  `json:"title"`
+	Status    int     `json:"status"`
+	Detalle   string  `json:"detail,omitempty"`
+	Instancia string  `json:"instance,omitempty"`
+	Codigo    string  `json:"codigo"`
+	Errores   []Campo `json:"errores,omitempty"`
+	// IDPeticion es el X-Request-ID de la petición, para buscarla en los logs
+	IDPeticion string `json:"request_id,omitempty"`
+	// Extra se agrega al mismo nivel que los demás miembros
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON agrega los miembros de Extra al objeto
+func (p Problema) MarshalJSON() ([]byte, error) {
+	type sinMetodos Problema
+	base, err := json.Marshal(sinMetodos(p))
+	if err != nil || len(p.Extra) == 0 {
+		return base, err
+	}
+
+	var combinado map[string]any
+	if err := json.Unmarshal(base, &combinado); err != nil {
+		return nil, err
+	}
+	for clave, valor := range p.Extra {
+		if _, existe := combinado[clave]; !existe {
+			combinado[clave] = valor
+		}
+	}
+	return json.Marshal(combinado)
+}
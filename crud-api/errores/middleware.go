@@ -0,0 +1,59 @@
+package errores
+
+import (
+	"errors"
+	"net/http"
+
+	"crud-api/registro"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware responde como problem+json el último error que un handler
+// registró con c.Error, si el handler no escribió ya una respuesta
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		Responder(c, c.Errors.Last().Err)
+	}
+}
+
+// Responder escribe err como problem+json. Los errores que no son *Error
+// se registran en el log y se responden como error interno.
+func Responder(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Interno(err)
+	}
+	if e.Status >= http.StatusInternalServerError && e.Causa != nil {
+		registro.Logger(c).Error("error al atender la petición",
+			"method", c.Request.Method, "path", c.Request.URL.Path, "error", e.Causa)
+	}
+
+	problema := e.Problema(c.Request.URL.RequestURI())
+	problema.IDPeticion = registro.ID(c)
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(e.Status, problema)
+}
+
+// RutaNoEncontrada es el handler para rutas que no existen
+func RutaNoEncontrada(c *gin.Context) {
+	Responder(c, Nuevo(http.StatusNotFound, CodigoRutaNoEncontrada, "La ruta no existe"))
+}
+
+// MetodoNoPermitido es el handler para métodos que la ruta no admite
+func MetodoNoPermitido(c *gin.Context) {
+	Responder(c, Nuevo(http.StatusMethodNotAllowed, CodigoMetodoNoPermitido, "Método no permitido para esta ruta"))
+}
+
+// Recuperar responde un panic como error interno; se usa con gin.CustomRecovery
+func Recuperar(c *gin.Context, recuperado any) {
+	registro.Logger(c).Error("panic al atender la petición",
+		"method", c.Request.Method, "path", c.Request.URL.Path, "panic", recuperado)
+	Responder(c, Nuevo(http.StatusInternalServerError, CodigoInterno, "Error interno del servidor"))
+}
@@ -0,0 +1,129 @@
+package errores
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Que los errores del validador usen el nombre JSON del campo
+	// ("precio") en lugar del nombre en Go ("Precio")
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(campo reflect.StructField) string {
+			nombre, _, _ := strings.Cut(campo.Tag.Get("json"), ",")
+			if nombre == "" || nombre == "-" {
+				return campo.Name
+			}
+			return nombre
+		})
+	}
+}
+
+// ErrorDeCampo lo implementan los errores de decodificación que saben qué
+// campo es inválido y por qué, como los de models.Producto con el precio
+type ErrorDeCampo interface {
+	error
+	CampoInvalido() (campo, mensaje string)
+}
+
+// DesdeBinding traduce el error de ShouldBindJSON o ValidateStruct: JSON
+// mal formado, tipos incorrectos o reglas de binding que no se cumplen
+func DesdeBinding(err error) *Error {
+	var errTamano *http.MaxBytesError
+	if errors.As(err, &errTamano) {
+		return Nuevo(http.StatusRequestEntityTooLarge, CodigoCuerpoDemasiadoGrande, "El cuerpo de la petición es demasiado grande").
+			ConDetalle("el máximo es %d bytes", errTamano.Limit)
+	}
+
+	var errSintaxis *json.SyntaxError
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &errSintaxis) {
+		return Nuevo(http.StatusBadRequest, CodigoJSONInvalido, "El cuerpo no es un JSON válido")
+	}
+
+	e := Nuevo(http.StatusBadRequest, CodigoValidacion, "Los datos enviados no son válidos")
+	e.Campos = CamposDeValidacion(err)
+	e.Causa = err
+	return e
+}
+
+// CamposDeValidacion retorna un mensaje por cada campo inválido
+func CamposDeValidacion(err error) []Campo {
+	var errTipo *json.UnmarshalTypeError
+	if errors.As(err, &errTipo) {
+		return []Campo{{Campo: errTipo.Field, Mensaje: mensajeTipo(errTipo.Type)}}
+	}
+
+	var errCampo ErrorDeCampo
+	if errors.As(err, &errCampo) {
+		campo, mensaje := errCampo.CampoInvalido()
+		return []Campo{{Campo: campo, Mensaje: mensaje}}
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return []Campo{{Mensaje: err.Error()}}
+	}
+
+	campos := make([]Campo, 0, len(errs))
+	for _, fe := range errs {
+		campos = append(campos, Campo{
+			Campo:   rutaCampo(fe),
+			Mensaje: mensajeRegla(fe),
+		})
+	}
+	return campos
+}
+
+// rutaCampo retorna el nombre del campo sin el nombre del struct raíz,
+// por ejemplo "precio" en lugar de "Producto.precio"
+func rutaCampo(fe validator.FieldError) string {
+	_, ruta, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return ruta
+}
+
+// mensajeTipo describe el tipo esperado de un campo
+func mensajeTipo(tipo reflect.Type) string {
+	switch tipo.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "debe ser un número"
+	case reflect.String:
+		return "debe ser un texto"
+	case reflect.Bool:
+		return "debe ser true o false"
+	}
+	return "tiene un tipo inválido"
+}
+
+// mensajeRegla arma un mensaje legible para la regla que falló
+func mensajeRegla(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "es obligatorio"
+	case "gt":
+		return fmt.Sprintf("debe ser mayor que %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("debe ser mayor o igual que %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("debe ser menor que %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("debe ser menor o igual que %s", fe.Param())
+	case "min":
+		return fmt.Sprintf("debe tener al menos %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("debe tener como máximo %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("debe ser uno de: %s", fe.Param())
+	}
+	return fmt.Sprintf("no cumple la regla %q", fe.Tag())
+}
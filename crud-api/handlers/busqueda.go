@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crud-api/models"
+	"crud-api/registro"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resultadoBusqueda es un producto encontrado con su relevancia
+type resultadoBusqueda struct {
+	Puntaje  float64         `json:"puntaje"`
+	Producto models.Producto `json:"producto"`
+}
+
+// BuscarProductos - GET /productos/search
+// Busca ?q= en el nombre y la descripción con el índice de texto y
+// retorna los productos del más relevante al menos, paginados con
+// ?page=&limit=. Sin distinguir acentos ni mayúsculas, cada palabra
+// encuentra también las que empiezan con ella y, salvo ?fuzzy=false, las
+// que tienen un error de tipeo. Admite ?currency= como GET /productos.
+func (h *ProductoHandler) BuscarProductos(c *gin.Context) {
+	texto := strings.TrimSpace(c.Query("q"))
+	if texto == "" {
+		c.Error(errParametroInvalido(fmt.Errorf("q es obligatorio")))
+		return
+	}
+	pagina, limite, err := parsearPaginacion(c)
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+	difuso := true
+	if valor := c.Query("fuzzy"); valor != "" {
+		if difuso, err = strconv.ParseBool(valor); err != nil {
+			c.Error(errParametroInvalido(fmt.Errorf("fuzzy debe ser true o false")))
+			return
+		}
+	}
+	moneda, err := monedaPedida(c)
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+
+	encontrados := h.indice.Buscar(texto, difuso)
+	desde := min((pagina-1)*limite, len(encontrados))
+	hasta := min(desde+limite, len(encontrados))
+
+	// Solo se leen del repositorio los productos de la página
+	var productos []models.Producto
+	var puntajes []float64
+	for _, encontrado := range encontrados[desde:hasta] {
+		producto, err := h.repo.Get(encontrado.ID)
+		if errors.Is(err, repository.ErrProductoNoEncontrado) || (err == nil && producto.EliminadoEn != nil) {
+			// Se eliminó entre la búsqueda y la lectura
+			continue
+		}
+		if err != nil {
+			c.Error(errorRepositorio(err))
+			return
+		}
+		productos = append(productos, producto)
+		puntajes = append(puntajes, encontrado.Puntaje)
+	}
+	productos, _, errMoneda := h.enMoneda(moneda, productos)
+	if errMoneda != nil {
+		c.Error(errMoneda)
+		return
+	}
+
+	resultados := make([]resultadoBusqueda, len(productos))
+	for i, producto := range productos {
+		resultados[i] = resultadoBusqueda{Puntaje: puntajes[i], Producto: producto}
+	}
+
+	totalPaginas := max(1, (len(encontrados)+limite-1)/limite)
+	links := gin.H{
+		"self":    c.Request.URL.RequestURI(),
+		"primera": urlConParametros(c, limite, map[string]string{"page": "1"}),
+		"ultima":  urlConParametros(c, limite, map[string]string{"page": strconv.Itoa(totalPaginas)}),
+	}
+	if hasta < len(encontrados) {
+		links["siguiente"] = urlConParametros(c, limite, map[string]string{"page": strconv.Itoa(pagina + 1)})
+	}
+	if pagina > 1 {
+		links["anterior"] = urlConParametros(c, limite, map[string]string{"page": strconv.Itoa(min(pagina-1, totalPaginas))})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resultados": resultados,
+		"total":      len(encontrados),
+		"pagina":     pagina,
+		"limite":     limite,
+		"links":      links,
+	})
+}
+
+// reindexar actualiza el índice de búsqueda con lo que el repositorio tiene
+// ahora de un producto. Se relee en vez de indexar lo que retornó la
+// escritura porque, con dos escrituras concurrentes, la que termina
+// después no siempre es la más nueva. Si falla, la búsqueda queda con la
+// versión anterior del producto hasta la próxima escritura.
+func (h *ProductoHandler) reindexar(c *gin.Context, id int) {
+	err := h.indice.Sincronizar(id, func(id int) (models.Producto, bool, error) {
+		producto, err := h.repo.Get(id)
+		if errors.Is(err, repository.ErrProductoNoEncontrado) {
+			return producto, false, nil
+		}
+		return producto, err == nil, err
+	})
+	if err != nil {
+		registro.Logger(c).Error("no se pudo actualizar el índice de búsqueda", "producto_id", id, "error", err)
+	}
+}
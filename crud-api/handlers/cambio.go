@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"crud-api/cambio"
+	"crud-api/dinero"
+	"crud-api/errores"
+	"crud-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CambioHandler publica y recarga la tabla de tipos de cambio
+type CambioHandler struct {
+	cambios *cambio.Cambios
+}
+
+// NuevoCambioHandler crea los handlers de tipos de cambio
+func NuevoCambioHandler(cambios *cambio.Cambios) *CambioHandler {
+	return &CambioHandler{cambios: cambios}
+}
+
+// TiposDeCambio - GET /tipos-de-cambio
+// Retorna la tabla vigente
+func (h *CambioHandler) TiposDeCambio(c *gin.Context) {
+	tabla := h.cambios.Tabla()
+	if tabla == nil {
+		c.Error(errSinArchivoDeCambio())
+		return
+	}
+	c.JSON(http.StatusOK, respuestaTabla(tabla))
+}
+
+// RecargarTiposDeCambio - POST /admin/tipos-de-cambio/recargar
+// Vuelve a leer el archivo de tipos de cambio. Si no es válido responde el
+// motivo y se sigue usando la tabla anterior.
+func (h *CambioHandler) RecargarTiposDeCambio(c *gin.Context) {
+	tabla, err := h.cambios.Recargar()
+	if errors.Is(err, cambio.ErrSinArchivo) {
+		c.Error(errSinArchivoDeCambio())
+		return
+	}
+	if err != nil {
+		c.Error(errores.Nuevo(http.StatusInternalServerError, errores.CodigoTiposDeCambio, "No se pudo recargar la tabla de tipos de cambio").
+			ConDetalle("%v", err))
+		return
+	}
+	c.JSON(http.StatusOK, respuestaTabla(tabla))
+}
+
+// respuestaTabla es la representación JSON de una tabla
+func respuestaTabla(tabla *cambio.Tabla) gin.H {
+	return gin.H{
+		"base":       tabla.Base,
+		"fecha":      tabla.Fecha,
+		"cargada_en": tabla.CargadaEn,
+		"huella":     tabla.Huella,
+		"tasas":      tabla.Monedas(),
+	}
+}
+
+func errSinArchivoDeCambio() *errores.Error {
+	return errores.Nuevo(http.StatusNotFound, errores.CodigoNoEncontrado, "No hay tabla de tipos de cambio").
+		ConDetalle("el servidor no tiene configurado un archivo de tipos de cambio")
+}
+
+// monedaPedida lee ?currency=; vacío deja cada producto en su moneda
+func monedaPedida(c *gin.Context) (string, error) {
+	moneda := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	if moneda == "" {
+		return "", nil
+	}
+	if _, ok := dinero.Decimales(moneda); !ok {
+		return "", fmt.Errorf("currency debe ser un código de moneda ISO 4217, por ejemplo EUR")
+	}
+	return moneda, nil
+}
+
+// enMoneda pasa los productos a la moneda pedida, con el precio fijo de
+// cada uno o convertido con la tabla vigente
+func (h *ProductoHandler) enMoneda(moneda string, productos []models.Producto) ([]models.Producto, *cambio.Tabla, *errores.Error) {
+	tabla := h.cambios.Tabla()
+	convertidos, err := convertirConTabla(tabla, moneda, productos)
+	if err != nil {
+		return nil, nil, err
+	}
+	return convertidos, tabla, nil
+}
+
+// convertirConTabla pasa los productos a la moneda pedida con una tabla
+// dada, para usar la misma en varias partes de una respuesta
+func convertirConTabla(tabla *cambio.Tabla, moneda string, productos []models.Producto) ([]models.Producto, *errores.Error) {
+	if moneda == "" {
+		return productos, nil
+	}
+
+	convertidos := make([]models.Producto, len(productos))
+	for i, producto := range productos {
+		convertido, err := cambio.EnMoneda(tabla, producto, moneda)
+		if err != nil {
+			return nil, errores.Nuevo(http.StatusBadRequest, errores.CodigoSinTipoDeCambio, "No se puede mostrar el precio en esa moneda").
+				ConDetalle("producto %d: %v", producto.ID, err)
+		}
+		convertidos[i] = convertido
+	}
+	return convertidos, nil
+}
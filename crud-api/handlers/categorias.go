@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"crud-api/errores"
+	"crud-api/models"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// CategoriaHandler agrupa los handlers de la taxonomía de categorías. Usa
+// los handlers de productos para listar los de una categoría con la misma
+// paginación, orden, filtros y ?currency= que GET /productos.
+type CategoriaHandler struct {
+	repo      repository.Repositorio
+	productos *ProductoHandler
+}
+
+// NuevoCategoriaHandler crea los handlers de categorías
+func NuevoCategoriaHandler(repo repository.Repositorio, productos *ProductoHandler) *CategoriaHandler {
+	return &CategoriaHandler{repo: repo, productos: productos}
+}
+
+// nodoCategoria es una categoría con sus subcategorías, para ?tree=true
+type nodoCategoria struct {
+	models.Categoria
+	Subcategorias []nodoCategoria `json:"subcategorias"`
+}
+
+// ListarCategorias - GET /categorias
+// Retorna todas las categorías con su ruta, o con ?tree=true el árbol a
+// partir de las raíces
+func (h *CategoriaHandler) ListarCategorias(c *gin.Context) {
+	arbol, err := parsearBooleano(c, "tree")
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+
+	categorias, err := h.repo.ListCategorias()
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	categorias = conRutas(categorias)
+
+	if arbol {
+		c.JSON(http.StatusOK, gin.H{"categorias": armarArbol(categorias, 0), "total": len(categorias)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categorias": categorias, "total": len(categorias)})
+}
+
+// ObtenerCategoria - GET /categorias/:id
+// Retorna una categoría con su ruta
+func (h *CategoriaHandler) ObtenerCategoria(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	categoria, err := h.conRuta(id)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+
+	// La ruta cambia si se renombra un ancestro sin que cambie la versión
+	// de esta categoría, así que If-None-Match no se usa aquí
+	c.Header("ETag", etiquetaVersion(categoria.Version))
+	c.JSON(http.StatusOK, categoria)
+}
+
+// CrearCategoria - POST /categorias
+// Crea una categoría; sin padre_id es una raíz
+func (h *CategoriaHandler) CrearCategoria(c *gin.Context) {
+	var nueva models.Categoria
+	if err := c.ShouldBindJSON(&nueva); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	creada, err := h.repo.CreateCategoria(nueva)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+	h.responder(c, http.StatusCreated, creada)
+}
+
+// ActualizarCategoria - PUT /categorias/:id
+// Reemplaza el nombre y el padre de una categoría; sin padre_id pasa a
+// ser una raíz. Las subcategorías y los productos la acompañan.
+func (h *CategoriaHandler) ActualizarCategoria(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	var categoria models.Categoria
+	if err := c.ShouldBindJSON(&categoria); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	version, ok := h.versionEsperada(c, id)
+	if !ok {
+		return
+	}
+	categoria.Version = version
+
+	actualizada, err := h.repo.UpdateCategoria(id, categoria)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+	h.responder(c, http.StatusOK, actualizada)
+}
+
+// ModificarCategoria - PATCH /categorias/:id
+// Renombra ({"nombre": ...}) o mueve ({"padre_id": ...}; null la pasa a
+// raíz) una categoría. Acepta JSON Merge Patch y JSON Patch, igual que
+// PATCH /productos/:id.
+func (h *CategoriaHandler) ModificarCategoria(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	aplicar, patch, ok := leerPatch(c)
+	if !ok {
+		return
+	}
+
+	actual, err := h.repo.GetCategoria(id)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+	if header := c.GetHeader("If-Match"); header != "" && !coincideETag(header, etiquetaVersion(actual.Version), false) {
+		c.Error(errCategoriaModificada())
+		return
+	}
+	documento, err := json.Marshal(actual)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+
+	modificado, err := aplicar(documento, patch)
+	if err != nil {
+		c.Error(errorPatch(err, "La categoría no coincide con la operación test"))
+		return
+	}
+
+	var categoria models.Categoria
+	if err := json.Unmarshal(modificado, &categoria); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&categoria); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	categoria.Version = actual.Version
+	actualizada, err := h.repo.UpdateCategoria(id, categoria)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+	h.responder(c, http.StatusOK, actualizada)
+}
+
+// EliminarCategoria - DELETE /categorias/:id
+// Elimina una categoría que no tiene subcategorías ni productos
+func (h *CategoriaHandler) EliminarCategoria(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	version, ok := h.versionEsperada(c, id)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteCategoria(id, version); err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mensaje": "Categoría eliminada exitosamente",
+	})
+}
+
+// ProductosDeCategoria - GET /categorias/:id/productos
+// Retorna los productos de la categoría; con ?recursive=true también los
+// de todas sus subcategorías. Admite los mismos parámetros que
+// GET /productos.
+func (h *CategoriaHandler) ProductosDeCategoria(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+	recursivo, err := parsearBooleano(c, "recursive")
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+	consulta, err := parsearConsulta(c)
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+
+	categorias, err := h.repo.ListCategorias()
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	existe := slices.ContainsFunc(categorias, func(categoria models.Categoria) bool {
+		return categoria.ID == id
+	})
+	if !existe {
+		c.Error(errorCategoria(repository.ErrCategoriaNoEncontrada))
+		return
+	}
+
+	incluidas := map[int]bool{id: true}
+	if recursivo {
+		for _, descendiente := range descendientes(categorias, id) {
+			incluidas[descendiente] = true
+		}
+	}
+
+	var productos []models.Producto
+	err = h.repo.Each(func(producto models.Producto) error {
+		if incluidas[producto.CategoriaID] {
+			productos = append(productos, producto)
+		}
+		return nil
+	})
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	h.productos.responderListado(c, consulta, productos)
+}
+
+// responder envía una categoría recién guardada con su ruta y su ETag
+func (h *CategoriaHandler) responder(c *gin.Context, status int, categoria models.Categoria) {
+	conRuta, err := h.conRuta(categoria.ID)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return
+	}
+	c.Header("ETag", etiquetaVersion(conRuta.Version))
+	c.JSON(status, conRuta)
+}
+
+// conRuta retorna la categoría con su ruta calculada
+func (h *CategoriaHandler) conRuta(id int) (models.Categoria, error) {
+	categorias, err := h.repo.ListCategorias()
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	for _, categoria := range conRutas(categorias) {
+		if categoria.ID == id {
+			return categoria, nil
+		}
+	}
+	return models.Categoria{}, repository.ErrCategoriaNoEncontrada
+}
+
+// versionEsperada es la de los productos (ver etag.go), para categorías
+func (h *CategoriaHandler) versionEsperada(c *gin.Context, id int) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	actual, err := h.repo.GetCategoria(id)
+	if err != nil {
+		c.Error(errorCategoria(err))
+		return 0, false
+	}
+
+	if !coincideETag(header, etiquetaVersion(actual.Version), false) {
+		c.Error(errCategoriaModificada())
+		return 0, false
+	}
+	return actual.Version, true
+}
+
+// errorCategoria es errorRepositorio con los mensajes de categorías
+func errorCategoria(err error) *errores.Error {
+	if errors.Is(err, repository.ErrVersionNoCoincide) {
+		return errCategoriaModificada()
+	}
+	return errorRepositorio(err)
+}
+
+func errCategoriaModificada() *errores.Error {
+	return errores.Nuevo(http.StatusPreconditionFailed, errores.CodigoVersionNoCoincide, "La categoría fue modificada por otra petición")
+}
+
+// conRutas completa la ruta de cada categoría subiendo por sus padres
+func conRutas(categorias []models.Categoria) []models.Categoria {
+	porID := make(map[int]models.Categoria, len(categorias))
+	for _, categoria := range categorias {
+		porID[categoria.ID] = categoria
+	}
+
+	resultado := make([]models.Categoria, len(categorias))
+	for i, categoria := range categorias {
+		var ruta []string
+		for actual, ok := categoria, true; ok; actual, ok = porID[actual.PadreID] {
+			ruta = append(ruta, actual.Nombre)
+		}
+		for izq, der := 0, len(ruta)-1; izq < der; izq, der = izq+1, der-1 {
+			ruta[izq], ruta[der] = ruta[der], ruta[izq]
+		}
+		categoria.Ruta = ruta
+		resultado[i] = categoria
+	}
+	return resultado
+}
+
+// armarArbol retorna las subcategorías de padre, cada una con las suyas,
+// ordenadas por nombre
+func armarArbol(categorias []models.Categoria, padre int) []nodoCategoria {
+	nodos := []nodoCategoria{}
+	for _, categoria := range categorias {
+		if categoria.PadreID == padre {
+			nodos = append(nodos, nodoCategoria{Categoria: categoria, Subcategorias: armarArbol(categorias, categoria.ID)})
+		}
+	}
+	sort.Slice(nodos, func(i, j int) bool {
+		return strings.ToLower(nodos[i].Nombre) < strings.ToLower(nodos[j].Nombre)
+	})
+	return nodos
+}
+
+// descendientes retorna los IDs de todas las subcategorías de id, a
+// cualquier profundidad
+func descendientes(categorias []models.Categoria, id int) []int {
+	hijas := map[int][]int{}
+	for _, categoria := range categorias {
+		hijas[categoria.PadreID] = append(hijas[categoria.PadreID], categoria.ID)
+	}
+
+	var resultado []int
+	pendientes := hijas[id]
+	for len(pendientes) > 0 {
+		actual := pendientes[0]
+		pendientes = pendientes[1:]
+		resultado = append(resultado, actual)
+		pendientes = append(pendientes, hijas[actual]...)
+	}
+	return resultado
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"crud-api/errores"
+	"crud-api/repository"
+)
+
+// errIDInvalido se usa cuando el parámetro :id no es un número
+func errIDInvalido() *errores.Error {
+	return errores.Nuevo(http.StatusBadRequest, errores.CodigoIDInvalido, "ID inválido")
+}
+
+// errParametroInvalido se usa cuando un query param no es válido
+func errParametroInvalido(err error) *errores.Error {
+	return errores.Nuevo(http.StatusBadRequest, errores.CodigoParametroInvalido, "Parámetro de consulta inválido").
+		ConDetalle("%v", err)
+}
+
+// errorRepositorio traduce un error del repositorio al error de la API
+func errorRepositorio(err error) *errores.Error {
+	switch {
+	case errors.Is(err, repository.ErrProductoNoEncontrado):
+		return errores.Nuevo(http.StatusNotFound, errores.CodigoNoEncontrado, "Producto no encontrado")
+	case errors.Is(err, repository.ErrVersionNoCoincide):
+		return errores.Nuevo(http.StatusPreconditionFailed, errores.CodigoVersionNoCoincide, "El producto fue modificado por otra petición")
+	case errors.Is(err, repository.ErrSKUDuplicado):
+		return errores.Nuevo(http.StatusConflict, errores.CodigoSKUDuplicado, "Ya existe un producto con ese SKU")
+	case errors.Is(err, repository.ErrProductoNoEliminado):
+		return errores.Nuevo(http.StatusConflict, errores.CodigoProductoNoEliminado, "El producto no está en la papelera")
+	case errors.Is(err, repository.ErrCategoriaDesconocida):
+		return errCampoInexistente("categoria_id", "no existe esa categoría")
+	case errors.Is(err, repository.ErrCategoriaNoEncontrada):
+		return errores.Nuevo(http.StatusNotFound, errores.CodigoNoEncontrado, "Categoría no encontrada")
+	case errors.Is(err, repository.ErrPadreNoEncontrado):
+		return errCampoInexistente("padre_id", "no existe esa categoría")
+	case errors.Is(err, repository.ErrCategoriaDuplicada):
+		return errores.Nuevo(http.StatusConflict, errores.CodigoCategoriaDuplicada, "Ya existe una categoría con ese nombre en el mismo nivel")
+	case errors.Is(err, repository.ErrCicloCategorias):
+		return errores.Nuevo(http.StatusConflict, errores.CodigoCicloCategorias, "La categoría no puede quedar dentro de sí misma").
+			ConDetalle("el padre indicado es la propia categoría o una de sus subcategorías")
+	case errors.Is(err, repository.ErrCategoriaEnUso):
+		return errores.Nuevo(http.StatusConflict, errores.CodigoCategoriaEnUso, "La categoría tiene subcategorías o productos").
+			ConDetalle("hay que moverlos o eliminarlos antes de eliminar la categoría")
+	case errors.Is(err, repository.ErrLoteCancelado):
+		return errores.Nuevo(http.StatusFailedDependency, errores.CodigoLoteCancelado, "No se aplicó porque otra operación del lote falló")
+	}
+	return errores.Interno(err)
+}
+
+// errCampoInexistente es el error de validación de un campo que apunta a
+// algo que no existe
+func errCampoInexistente(campo, mensaje string) *errores.Error {
+	e := errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "Los datos enviados no son válidos")
+	e.Campos = []errores.Campo{{Campo: campo, Mensaje: mensaje}}
+	return e
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"crud-api/cambio"
+	"crud-api/models"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag retorna el ETag de un producto; cambia con cada modificación
+func etag(producto models.Producto) string {
+	return etiquetaVersion(producto.Version)
+}
+
+// etiquetaVersion es el ETag de un recurso con esa versión
+func etiquetaVersion(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagEnMoneda es el ETag de un producto devuelto con ?currency=. Si el
+// precio se convirtió, la etiqueta incluye la moneda y la tabla de tipos de
+// cambio, así recargar la tabla invalida las copias en caché; esa etiqueta
+// no sirve para If-Match porque no es la del producto guardado.
+func etagEnMoneda(producto models.Producto, tabla *cambio.Tabla) string {
+	if producto.Conversion == nil || tabla == nil {
+		return etag(producto)
+	}
+	return `"` + strconv.Itoa(producto.Version) + "-" + producto.Precio.Moneda() + "-" + tabla.Huella + `"`
+}
+
+// coincideETag reporta si un header If-Match o If-None-Match incluye la
+// etiqueta. If-Match usa comparación fuerte (las etiquetas W/ no coinciden)
+// e If-None-Match comparación débil.
+func coincideETag(header, etiqueta string, debil bool) bool {
+	for _, candidato := range strings.Split(header, ",") {
+		candidato = strings.TrimSpace(candidato)
+		if candidato == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidato, "W/") {
+			if !debil {
+				continue
+			}
+			candidato = candidato[2:]
+		}
+		if candidato == etiqueta {
+			return true
+		}
+	}
+	return false
+}
+
+// versionEsperada evalúa el header If-Match contra el producto actual.
+// Retorna la versión que se le pasa al repositorio (0 si no hay If-Match,
+// es decir, sin condición). Si la precondición falla responde y retorna false.
+func (h *ProductoHandler) versionEsperada(c *gin.Context, id int) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	actual, err := h.repo.Get(id)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return 0, false
+	}
+
+	if !coincideETag(header, etag(actual), false) {
+		responderPrecondicionFallida(c)
+		return 0, false
+	}
+	return actual.Version, true
+}
+
+// responderPrecondicionFallida responde 412 cuando If-Match no coincide
+func responderPrecondicionFallida(c *gin.Context) {
+	c.Error(errorRepositorio(repository.ErrVersionNoCoincide))
+}
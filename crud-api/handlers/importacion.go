@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"crud-api/dinero"
+	"crud-api/errores"
+	"crud-api/models"
+	"crud-api/registro"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	formatoCSV    = "csv"
+	formatoNDJSON = "ndjson"
+
+	// maxTamanoImportacion limita el cuerpo de POST /productos/import
+	maxTamanoImportacion = 10 << 20 // 10 MB
+	// maxErroresReportados acota la lista de errores de la respuesta
+	maxErroresReportados = 100
+	// filasPorFlush es cada cuántos productos se envía lo exportado al cliente
+	filasPorFlush = 100
+)
+
+// columnasCSV son las columnas que se exportan, en orden
+var columnasCSV = []string{
+	"id", "sku", "nombre", "descripcion", "categoria", "categoria_id", "precio", "moneda", "precios", "stock", "activo",
+	"creado_en", "actualizado_en", "version",
+}
+
+// inicioFormula son los caracteres con los que una celda se interpreta
+// como fórmula al abrir el CSV en una planilla de cálculo
+const inicioFormula = "=+-@\t\r"
+
+// inicioEscapado son los caracteres iniciales que textoCSV escapa: los de
+// fórmula y el propio apóstrofo
+const inicioEscapado = inicioFormula + "'"
+
+// columnasIgnoradas son las columnas del CSV que asigna el repositorio;
+// se aceptan para poder reimportar una exportación pero no se leen
+var columnasIgnoradas = []string{"id", "creado_en", "actualizado_en", "version"}
+
+// ExportarProductos - GET /productos/export?format=csv|ndjson
+// Envía el catálogo completo de a poco, sin armarlo entero en memoria
+func (h *ProductoHandler) ExportarProductos(c *gin.Context) {
+	formato := c.DefaultQuery("format", formatoCSV)
+
+	var escribir func(models.Producto) error
+	var terminar func() error
+	var encabezado func() error
+
+	switch formato {
+	case formatoCSV:
+		w := csv.NewWriter(c.Writer)
+		encabezado = func() error {
+			return w.Write(columnasCSV)
+		}
+		escribir = func(p models.Producto) error {
+			return w.Write([]string{
+				strconv.Itoa(p.ID),
+				textoCSV(p.SKU),
+				textoCSV(p.Nombre),
+				textoCSV(p.Descripcion),
+				textoCSV(p.Categoria),
+				strconv.Itoa(p.CategoriaID),
+				p.Precio.Decimal(),
+				p.Precio.Moneda(),
+				preciosCSV(p.Precios),
+				strconv.Itoa(p.Stock),
+				strconv.FormatBool(p.Activo),
+				p.CreadoEn.Format(time.RFC3339Nano),
+				p.ActualizadoEn.Format(time.RFC3339Nano),
+				strconv.Itoa(p.Version),
+			})
+		}
+		terminar = func() error {
+			w.Flush()
+			return w.Error()
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	case formatoNDJSON:
+		encoder := json.NewEncoder(c.Writer)
+		escribir = func(p models.Producto) error {
+			return encoder.Encode(p)
+		}
+		terminar = func() error { return nil }
+		encabezado = func() error { return nil }
+		c.Header("Content-Type", "application/x-ndjson")
+	default:
+		c.Error(errParametroInvalido(errors.New("format debe ser csv o ndjson")))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="productos.%s"`, formato))
+	c.Status(http.StatusOK)
+
+	filas := 0
+	err := encabezado()
+	if err == nil {
+		err = h.repo.Each(func(producto models.Producto) error {
+			// Los productos de la papelera no son parte del catálogo
+			if producto.EliminadoEn != nil {
+				return nil
+			}
+			if err := escribir(producto); err != nil {
+				return err
+			}
+			filas++
+			if filas%filasPorFlush == 0 {
+				if err := terminar(); err != nil {
+					return err
+				}
+				c.Writer.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = terminar()
+	}
+	if err != nil {
+		// Los headers ya se enviaron; solo queda cortar y registrarlo
+		registro.Logger(c).Error("error al exportar productos", "error", err)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// ImportarProductos - POST /productos/import?format=csv|ndjson
+// Crea los productos del archivo asignando IDs igual que CrearProducto.
+// Si alguna fila no es válida no se importa nada y se responde con los
+// errores de cada fila.
+func (h *ProductoHandler) ImportarProductos(c *gin.Context) {
+	formato := c.Query("format")
+	if formato == "" {
+		formato = formatoPorContentType(c.GetHeader("Content-Type"))
+	}
+
+	cuerpo := http.MaxBytesReader(c.Writer, c.Request.Body, maxTamanoImportacion)
+
+	var filas []filaImportada
+	var filasInvalidas []errores.Campo
+	var err error
+
+	switch formato {
+	case formatoCSV:
+		filas, filasInvalidas, err = leerCSV(cuerpo)
+	case formatoNDJSON:
+		filas, filasInvalidas, err = leerNDJSON(cuerpo)
+	default:
+		c.Error(errores.Nuevo(http.StatusUnsupportedMediaType, errores.CodigoTipoNoSoportado, "Tipo de contenido no soportado").
+			ConDetalle("format debe ser csv o ndjson (o Content-Type text/csv / application/x-ndjson)"))
+		return
+	}
+
+	if err != nil {
+		var errTamano *http.MaxBytesError
+		if errors.As(err, &errTamano) {
+			c.Error(errores.DesdeBinding(err))
+			return
+		}
+		c.Error(errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "No se pudo leer el archivo").
+			ConDetalle("%v", err))
+		return
+	}
+
+	if len(filasInvalidas) > 0 {
+		e := errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "El archivo tiene filas inválidas; no se importó ningún producto").
+			ConExtra("total_errores", len(filasInvalidas))
+		e.Campos = filasInvalidas[:min(len(filasInvalidas), maxErroresReportados)]
+		c.Error(e)
+		return
+	}
+	if len(filas) == 0 {
+		c.Error(errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "El archivo no tiene productos"))
+		return
+	}
+
+	// Todo o nada, así un error a mitad de camino no deja el catálogo a medias
+	operaciones := make([]repository.OperacionLote, len(filas))
+	for i, fila := range filas {
+		operaciones[i] = repository.OperacionLote{Op: repository.OpCrear, Producto: fila.producto}
+	}
+	resultados, err := h.repo.Bulk(operaciones, true)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	for i, resultado := range resultados {
+		if resultado.Err != nil && !errors.Is(resultado.Err, repository.ErrLoteCancelado) {
+			c.Error(errorFilaImportada(filas[i], resultado.Err))
+			return
+		}
+	}
+	for _, resultado := range resultados {
+		h.reindexar(c, resultado.Producto.ID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"importados": len(resultados),
+		"primer_id":  resultados[0].Producto.ID,
+		"ultimo_id":  resultados[len(resultados)-1].Producto.ID,
+	})
+}
+
+// filaImportada es un producto leído del archivo y la línea de donde salió
+type filaImportada struct {
+	linea    int
+	producto models.Producto
+}
+
+// errorFilaImportada traduce el error del repositorio al crear el producto
+// de una fila e indica la línea, como los errores de validación
+func errorFilaImportada(fila filaImportada, err error) *errores.Error {
+	e := errorRepositorio(err)
+	campo := errores.Campo{Linea: fila.linea}
+	switch {
+	case errors.Is(err, repository.ErrSKUDuplicado):
+		campo.Campo = "sku"
+		campo.Mensaje = fmt.Sprintf("el SKU %q ya existe o está repetido en el archivo", fila.producto.SKU)
+	case errors.Is(err, repository.ErrCategoriaDesconocida):
+		campo.Campo = "categoria_id"
+		campo.Mensaje = fmt.Sprintf("la categoría %d no existe", fila.producto.CategoriaID)
+	default:
+		// Un error interno no se detalla al cliente
+		return e
+	}
+	e.ConDetalle("línea %d: %s; no se importó ningún producto", campo.Linea, campo.Mensaje)
+	e.Campos = []errores.Campo{campo}
+	return e
+}
+
+// formatoPorContentType deduce el formato de importación del Content-Type
+func formatoPorContentType(contentType string) string {
+	tipo, _, _ := mime.ParseMediaType(contentType)
+	switch tipo {
+	case "text/csv":
+		return formatoCSV
+	case "application/x-ndjson", "application/jsonl":
+		return formatoNDJSON
+	}
+	return ""
+}
+
+// leerCSV lee productos de un CSV con encabezado. Las columnas id,
+// version y las fechas se ignoran (las asigna el repositorio); nombre y
+// precio son obligatorias y las demás toman los valores por defecto del
+// modelo. Retorna err solo si el archivo no se puede leer.
+func leerCSV(r io.Reader) ([]filaImportada, []errores.Campo, error) {
+	lector := csv.NewReader(r)
+	lector.FieldsPerRecord = -1
+
+	encabezado, err := lector.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("línea 1: %w", err)
+	}
+
+	// Posición de cada columna conocida
+	columnas := map[string]int{}
+	var invalidas []errores.Campo
+	for i, nombre := range encabezado {
+		nombre = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(nombre, "\ufeff")))
+		if !slices.Contains(columnasCSV, nombre) {
+			invalidas = append(invalidas, errores.Campo{Linea: 1, Campo: nombre, Mensaje: "columna desconocida"})
+			continue
+		}
+		if !slices.Contains(columnasIgnoradas, nombre) {
+			columnas[nombre] = i
+		}
+	}
+	for _, obligatoria := range []string{"nombre", "precio"} {
+		if _, ok := columnas[obligatoria]; !ok {
+			invalidas = append(invalidas, errores.Campo{Linea: 1, Campo: obligatoria, Mensaje: "falta la columna"})
+		}
+	}
+	if len(invalidas) > 0 {
+		return nil, invalidas, nil
+	}
+
+	var filas []filaImportada
+	for {
+		registro, err := lector.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var errCSV *csv.ParseError
+			if errors.As(err, &errCSV) {
+				invalidas = append(invalidas, errores.Campo{Linea: errCSV.Line, Mensaje: errCSV.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		linea, _ := lector.FieldPos(0)
+
+		valor := func(columna string) string {
+			i, ok := columnas[columna]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return strings.TrimSpace(registro[i])
+		}
+
+		producto := models.NuevoProducto()
+		producto.SKU = sinEscapeFormula(valor("sku"))
+		producto.Nombre = sinEscapeFormula(valor("nombre"))
+		producto.Descripcion = sinEscapeFormula(valor("descripcion"))
+		producto.Categoria = sinEscapeFormula(valor("categoria"))
+		moneda := models.MonedaPorDefecto
+		if texto := valor("moneda"); texto != "" {
+			moneda = texto
+		}
+
+		var filaErrores []errores.Campo
+		precio, err := dinero.Nuevo(0, moneda)
+		if texto := valor("precio"); texto != "" && err == nil {
+			precio, err = dinero.Parsear(texto, moneda)
+		}
+		switch {
+		case errors.Is(err, dinero.ErrMonedaDesconocida):
+			filaErrores = append(filaErrores, errores.Campo{Linea: linea, Campo: "moneda", Mensaje: err.Error()})
+		case err != nil:
+			filaErrores = append(filaErrores, errores.Campo{Linea: linea, Campo: "precio", Mensaje: err.Error()})
+		}
+		producto.Precio = precio
+		if texto := valor("precios"); texto != "" {
+			if err := json.Unmarshal([]byte(texto), &producto.Precios); err != nil {
+				filaErrores = append(filaErrores, campoPrecios(linea, err))
+			} else if err := producto.ValidarPrecios(); err != nil {
+				filaErrores = append(filaErrores, campoPrecios(linea, err))
+			}
+		}
+		if texto := valor("categoria_id"); texto != "" {
+			categoriaID, err := strconv.Atoi(texto)
+			if err != nil || categoriaID < 0 {
+				filaErrores = append(filaErrores, errores.Campo{Linea: linea, Campo: "categoria_id", Mensaje: "debe ser un número entero mayor o igual que 0"})
+			}
+			producto.CategoriaID = categoriaID
+		}
+		if texto := valor("stock"); texto != "" {
+			stock, err := strconv.Atoi(texto)
+			if err != nil {
+				filaErrores = append(filaErrores, errores.Campo{Linea: linea, Campo: "stock", Mensaje: "debe ser un número entero"})
+			}
+			producto.Stock = stock
+		}
+		if texto := valor("activo"); texto != "" {
+			activo, err := strconv.ParseBool(texto)
+			if err != nil {
+				filaErrores = append(filaErrores, errores.Campo{Linea: linea, Campo: "activo", Mensaje: "debe ser true o false"})
+			}
+			producto.Activo = activo
+		}
+		if len(filaErrores) > 0 {
+			invalidas = append(invalidas, filaErrores...)
+			continue
+		}
+
+		if filaErrores := validarFila(linea, producto); len(filaErrores) > 0 {
+			invalidas = append(invalidas, filaErrores...)
+			continue
+		}
+		filas = append(filas, filaImportada{linea: linea, producto: producto})
+	}
+
+	return filas, invalidas, nil
+}
+
+// textoCSV escapa una celda de texto que una planilla de cálculo tomaría
+// como fórmula (por ejemplo "=HYPERLINK(...)") anteponiéndole un apóstrofo.
+// Un texto que ya empieza con apóstrofo también lo lleva, así al importar
+// se sabe que el primero es del escape.
+func textoCSV(texto string) string {
+	if texto != "" && strings.ContainsRune(inicioEscapado, rune(texto[0])) {
+		return "'" + texto
+	}
+	return texto
+}
+
+// sinEscapeFormula deshace textoCSV, así una exportación se reimporta sin
+// cambios. Solo quita el apóstrofo que textoCSV pudo haber agregado; en
+// "'hola" es parte del texto.
+func sinEscapeFormula(texto string) string {
+	if len(texto) > 1 && texto[0] == '\'' && strings.ContainsRune(inicioEscapado, rune(texto[1])) {
+		return texto[1:]
+	}
+	return texto
+}
+
+// preciosCSV escribe los precios fijos como un objeto JSON en una celda;
+// sin precios fijos la celda queda vacía
+func preciosCSV(precios models.Precios) string {
+	if len(precios) == 0 {
+		return ""
+	}
+	datos, _ := json.Marshal(precios)
+	return string(datos)
+}
+
+// campoPrecios es el error de la celda de precios fijos de una fila
+func campoPrecios(linea int, err error) errores.Campo {
+	var errCampo errores.ErrorDeCampo
+	if errors.As(err, &errCampo) {
+		campo, mensaje := errCampo.CampoInvalido()
+		return errores.Campo{Linea: linea, Campo: campo, Mensaje: mensaje}
+	}
+	return errores.Campo{Linea: linea, Campo: "precios", Mensaje: `debe ser un objeto JSON, por ejemplo {"EUR": "829.00"}`}
+}
+
+// leerNDJSON lee un producto JSON por línea; las líneas vacías se ignoran
+func leerNDJSON(r io.Reader) ([]filaImportada, []errores.Campo, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var filas []filaImportada
+	var invalidas []errores.Campo
+	linea := 0
+
+	for scanner.Scan() {
+		linea++
+		texto := bytes.TrimSpace(scanner.Bytes())
+		if len(texto) == 0 {
+			continue
+		}
+
+		var producto models.Producto
+		if err := json.Unmarshal(texto, &producto); err != nil {
+			var errTipo *json.UnmarshalTypeError
+			var errCampo errores.ErrorDeCampo
+			if !errors.As(err, &errTipo) && !errors.As(err, &errCampo) {
+				invalidas = append(invalidas, errores.Campo{Linea: linea, Mensaje: "JSON inválido"})
+				continue
+			}
+			for _, campo := range errores.CamposDeValidacion(err) {
+				campo.Linea = linea
+				invalidas = append(invalidas, campo)
+			}
+			continue
+		}
+
+		// El ID, la versión y las fechas los asigna el repositorio
+		producto.ID, producto.Version = 0, 0
+		producto.CreadoEn, producto.ActualizadoEn = time.Time{}, time.Time{}
+
+		if filaErrores := validarFila(linea, producto); len(filaErrores) > 0 {
+			invalidas = append(invalidas, filaErrores...)
+			continue
+		}
+		filas = append(filas, filaImportada{linea: linea, producto: producto})
+	}
+
+	return filas, invalidas, scanner.Err()
+}
+
+// validarFila aplica las reglas de binding del modelo a una fila
+func validarFila(linea int, producto models.Producto) []errores.Campo {
+	err := binding.Validator.ValidateStruct(&producto)
+	if err == nil {
+		return nil
+	}
+
+	var invalidas []errores.Campo
+	for _, campo := range errores.CamposDeValidacion(err) {
+		campo.Linea = linea
+		invalidas = append(invalidas, campo)
+	}
+	return invalidas
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"crud-api/dinero"
+	"crud-api/errores"
+	"crud-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	limitePorDefecto = 20
+	limiteMaximo     = 100
+)
+
+// consultaListado son los parámetros de GET /productos ya validados
+type consultaListado struct {
+	pagina    int
+	limite    int
+	despuesDe *models.Producto // cursor decodificado de ?after=
+	orden     []campoOrden
+	precioMin *big.Rat
+	precioMax *big.Rat
+	texto     string
+	// categoriaID es ?categoria_id=: solo los productos de esa categoría de
+	// la taxonomía (0 son los que no están en ninguna)
+	categoriaID *int
+	activo      *bool
+	moneda      string // ?currency=; vacío deja cada precio en su moneda
+	// incluirEliminados es ?include_deleted=true: lista también la papelera
+	incluirEliminados bool
+}
+
+// campoOrden es un criterio de ordenamiento, por ejemplo "-precio"
+type campoOrden struct {
+	campo       string
+	descendente bool
+}
+
+// parsearConsulta lee y valida los query params de paginación, orden y filtros
+func parsearConsulta(c *gin.Context) (consultaListado, error) {
+	var consulta consultaListado
+	var err error
+	if consulta.pagina, consulta.limite, err = parsearPaginacion(c); err != nil {
+		return consulta, err
+	}
+
+	if valor := c.Query("sort"); valor != "" {
+		for _, parte := range strings.Split(valor, ",") {
+			criterio := campoOrden{campo: strings.TrimSpace(parte)}
+			if strings.HasPrefix(criterio.campo, "-") {
+				criterio.descendente = true
+				criterio.campo = criterio.campo[1:]
+			}
+			switch criterio.campo {
+			case "id", "sku", "nombre", "precio", "stock", "creado_en", "actualizado_en":
+			default:
+				return consulta, fmt.Errorf("sort solo admite id, sku, nombre, precio, stock, creado_en o actualizado_en (con - para descendente)")
+			}
+			consulta.orden = append(consulta.orden, criterio)
+		}
+	}
+
+	// El cursor tiene los valores de los campos de orden, así que se lee
+	// después de sort
+	if valor := c.Query("after"); valor != "" {
+		cursor, err := consulta.decodificarCursor(valor)
+		if err != nil {
+			return consulta, fmt.Errorf("after no es un cursor válido para este sort")
+		}
+		consulta.despuesDe = &cursor
+	}
+
+	if consulta.precioMin, err = parsearPrecio(c, "precio_min"); err != nil {
+		return consulta, err
+	}
+	if consulta.precioMax, err = parsearPrecio(c, "precio_max"); err != nil {
+		return consulta, err
+	}
+	if consulta.precioMin != nil && consulta.precioMax != nil && consulta.precioMin.Cmp(consulta.precioMax) > 0 {
+		return consulta, fmt.Errorf("precio_min no puede ser mayor que precio_max")
+	}
+
+	if valor := c.Query("activo"); valor != "" {
+		activo, err := strconv.ParseBool(valor)
+		if err != nil {
+			return consulta, fmt.Errorf("activo debe ser true o false")
+		}
+		consulta.activo = &activo
+	}
+
+	if consulta.moneda, err = monedaPedida(c); err != nil {
+		return consulta, err
+	}
+	if consulta.incluirEliminados, err = parsearBooleano(c, "include_deleted"); err != nil {
+		return consulta, err
+	}
+
+	if valor := c.Query("categoria_id"); valor != "" {
+		id, err := strconv.Atoi(valor)
+		if err != nil || id < 0 {
+			return consulta, fmt.Errorf("categoria_id debe ser un entero mayor o igual a 0")
+		}
+		consulta.categoriaID = &id
+	}
+	// La etiqueta libre está obsoleta; ignorar el filtro en silencio
+	// devolvería todo el catálogo a quien todavía lo usa
+	if c.Query("categoria") != "" {
+		return consulta, fmt.Errorf("categoria ya no se admite como filtro: use categoria_id con el ID de la categoría (GET /categorias)")
+	}
+
+	consulta.texto = strings.ToLower(strings.TrimSpace(c.Query("q")))
+	return consulta, nil
+}
+
+// parsearPaginacion lee ?page= y ?limit=, con sus valores por defecto
+func parsearPaginacion(c *gin.Context) (pagina, limite int, err error) {
+	pagina, limite = 1, limitePorDefecto
+
+	if valor := c.Query("page"); valor != "" {
+		pagina, err = strconv.Atoi(valor)
+		if err != nil || pagina < 1 {
+			return 0, 0, fmt.Errorf("page debe ser un entero mayor o igual a 1")
+		}
+	}
+
+	if valor := c.Query("limit"); valor != "" {
+		limite, err = strconv.Atoi(valor)
+		if err != nil || limite < 1 || limite > limiteMaximo {
+			return 0, 0, fmt.Errorf("limit debe ser un entero entre 1 y %d", limiteMaximo)
+		}
+	}
+	return pagina, limite, nil
+}
+
+// parsearBooleano lee un query param true/false; ausente es false
+func parsearBooleano(c *gin.Context, nombre string) (bool, error) {
+	valor := c.Query(nombre)
+	if valor == "" {
+		return false, nil
+	}
+	booleano, err := strconv.ParseBool(valor)
+	if err != nil {
+		return false, fmt.Errorf("%s debe ser true o false", nombre)
+	}
+	return booleano, nil
+}
+
+// parsearPrecio lee un query param numérico opcional. Es un valor exacto
+// y se compara con el precio de cada producto en su propia moneda, o en la
+// de ?currency= si se indica.
+func parsearPrecio(c *gin.Context, nombre string) (*big.Rat, error) {
+	valor := c.Query(nombre)
+	if valor == "" {
+		return nil, nil
+	}
+	precio, ok := new(big.Rat).SetString(valor)
+	if !ok {
+		return nil, fmt.Errorf("%s debe ser un número", nombre)
+	}
+	return precio, nil
+}
+
+// filtrar retorna los productos que cumplen los filtros de la consulta
+// que no dependen del precio
+func (q consultaListado) filtrar(productos []models.Producto) []models.Producto {
+	filtrados := make([]models.Producto, 0, len(productos))
+	for _, producto := range productos {
+		if producto.EliminadoEn != nil && !q.incluirEliminados {
+			continue
+		}
+		if q.texto != "" && !strings.Contains(strings.ToLower(producto.Nombre), q.texto) {
+			continue
+		}
+		if q.categoriaID != nil && producto.CategoriaID != *q.categoriaID {
+			continue
+		}
+		if q.activo != nil && producto.Activo != *q.activo {
+			continue
+		}
+		filtrados = append(filtrados, producto)
+	}
+	return filtrados
+}
+
+// filtrarPrecio retorna los productos dentro de ?precio_min= y ?precio_max=
+func (q consultaListado) filtrarPrecio(productos []models.Producto) []models.Producto {
+	filtrados := make([]models.Producto, 0, len(productos))
+	for _, producto := range productos {
+		if q.precioMin != nil && producto.Precio.Rat().Cmp(q.precioMin) < 0 {
+			continue
+		}
+		if q.precioMax != nil && producto.Precio.Rat().Cmp(q.precioMax) > 0 {
+			continue
+		}
+		filtrados = append(filtrados, producto)
+	}
+	return filtrados
+}
+
+// usaPrecio indica si la consulta filtra u ordena por precio
+func (q consultaListado) usaPrecio() bool {
+	if q.precioMin != nil || q.precioMax != nil {
+		return true
+	}
+	return slices.ContainsFunc(q.orden, func(criterio campoOrden) bool { return criterio.campo == "precio" })
+}
+
+// comparar ordena dos productos según los criterios de la consulta.
+// El ID desempata siempre, así el orden es total y los cursores estables.
+func (q consultaListado) comparar(a, b models.Producto) int {
+	for _, criterio := range q.orden {
+		var resultado int
+		switch criterio.campo {
+		case "id":
+			resultado = compararValores(a.ID, b.ID)
+		case "nombre":
+			resultado = strings.Compare(strings.ToLower(a.Nombre), strings.ToLower(b.Nombre))
+		case "sku":
+			resultado = strings.Compare(a.SKU, b.SKU)
+		case "precio":
+			resultado = dinero.CompararValor(a.Precio, b.Precio)
+		case "stock":
+			resultado = compararValores(a.Stock, b.Stock)
+		case "creado_en":
+			resultado = a.CreadoEn.Compare(b.CreadoEn)
+		case "actualizado_en":
+			resultado = a.ActualizadoEn.Compare(b.ActualizadoEn)
+		}
+		if criterio.descendente {
+			resultado = -resultado
+		}
+		if resultado != 0 {
+			return resultado
+		}
+	}
+	return compararValores(a.ID, b.ID)
+}
+
+func compararValores(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// resultadoListado es una página de productos y los datos para navegar
+type resultadoListado struct {
+	productos       []models.Producto
+	total           int
+	desde           int // posición del primer producto de la página
+	siguienteCursor string
+}
+
+// aplicar filtra, ordena y recorta la página pedida. convertir pasa los
+// precios a la moneda de la consulta y se llama solo con los productos que
+// hace falta: los que cumplen los demás filtros si se filtra u ordena por
+// precio, si no solo los de la página. Así un producto que no se muestra
+// no hace fallar la consulta por no tener tipo de cambio.
+func (q consultaListado) aplicar(productos []models.Producto, convertir func([]models.Producto) ([]models.Producto, *errores.Error)) (resultadoListado, *errores.Error) {
+	filtrados := q.filtrar(productos)
+	porPrecio := q.usaPrecio()
+	if porPrecio {
+		convertidos, err := convertir(filtrados)
+		if err != nil {
+			return resultadoListado{}, err
+		}
+		filtrados = q.filtrarPrecio(convertidos)
+	}
+	sort.SliceStable(filtrados, func(i, j int) bool {
+		return q.comparar(filtrados[i], filtrados[j]) < 0
+	})
+
+	resultado := resultadoListado{total: len(filtrados)}
+
+	// Con cursor se empieza justo después del último producto visto;
+	// si no, se usa la página
+	if q.despuesDe != nil {
+		resultado.desde = sort.Search(len(filtrados), func(i int) bool {
+			return q.comparar(filtrados[i], *q.despuesDe) > 0
+		})
+	} else {
+		resultado.desde = min((q.pagina-1)*q.limite, len(filtrados))
+	}
+
+	hasta := min(resultado.desde+q.limite, len(filtrados))
+	resultado.productos = filtrados[resultado.desde:hasta]
+
+	if hasta < len(filtrados) {
+		resultado.siguienteCursor = q.codificarCursor(filtrados[hasta-1])
+	}
+	if !porPrecio {
+		convertidos, err := convertir(resultado.productos)
+		if err != nil {
+			return resultadoListado{}, err
+		}
+		resultado.productos = convertidos
+	}
+	return resultado, nil
+}
+
+// links arma las URLs de navegación conservando los filtros de la petición
+func (q consultaListado) links(c *gin.Context, r resultadoListado) gin.H {
+	armar := func(cambios map[string]string) string {
+		return urlConParametros(c, q.limite, cambios)
+	}
+
+	totalPaginas := max(1, (r.total+q.limite-1)/q.limite)
+	links := gin.H{
+		"self":    c.Request.URL.RequestURI(),
+		"primera": armar(map[string]string{"page": "1"}),
+		"ultima":  armar(map[string]string{"page": strconv.Itoa(totalPaginas)}),
+	}
+
+	if r.siguienteCursor != "" {
+		if q.despuesDe != nil {
+			links["siguiente"] = armar(map[string]string{"after": r.siguienteCursor})
+		} else {
+			links["siguiente"] = armar(map[string]string{"page": strconv.Itoa(q.pagina + 1)})
+		}
+	}
+	if q.despuesDe == nil && q.pagina > 1 {
+		links["anterior"] = armar(map[string]string{"page": strconv.Itoa(min(q.pagina-1, totalPaginas))})
+	}
+	return links
+}
+
+// urlConParametros arma la URL de la petición con otra página o cursor,
+// conservando los demás query params
+func urlConParametros(c *gin.Context, limite int, cambios map[string]string) string {
+	params := url.Values{}
+	for clave, valores := range c.Request.URL.Query() {
+		params[clave] = valores
+	}
+	params.Del("page")
+	params.Del("after")
+	for clave, valor := range cambios {
+		params.Set(clave, valor)
+	}
+	params.Set("limit", strconv.Itoa(limite))
+	return c.Request.URL.Path + "?" + params.Encode()
+}
+
+// codificarCursor genera un cursor opaco con los valores del producto en
+// los campos de orden de la consulta y al final su ID, que desempata. El
+// precio va como "<decimal> <moneda>".
+func (q consultaListado) codificarCursor(producto models.Producto) string {
+	valores := make([]any, 0, len(q.orden)+1)
+	for _, criterio := range q.orden {
+		switch criterio.campo {
+		case "id":
+			valores = append(valores, producto.ID)
+		case "sku":
+			valores = append(valores, producto.SKU)
+		case "nombre":
+			valores = append(valores, producto.Nombre)
+		case "precio":
+			valores = append(valores, producto.Precio.Decimal()+" "+producto.Precio.Moneda())
+		case "stock":
+			valores = append(valores, producto.Stock)
+		case "creado_en":
+			valores = append(valores, producto.CreadoEn)
+		case "actualizado_en":
+			valores = append(valores, producto.ActualizadoEn)
+		}
+	}
+	valores = append(valores, producto.ID)
+
+	datos, _ := json.Marshal(valores)
+	return base64.RawURLEncoding.EncodeToString(datos)
+}
+
+// decodificarCursor recupera el producto de referencia de un cursor, con
+// solo los campos de orden y el ID. Falla si el cursor se generó con otro
+// sort.
+func (q consultaListado) decodificarCursor(cursor string) (models.Producto, error) {
+	var producto models.Producto
+	datos, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return producto, err
+	}
+	var valores []json.RawMessage
+	if err := json.Unmarshal(datos, &valores); err != nil {
+		return producto, err
+	}
+	if len(valores) != len(q.orden)+1 {
+		return producto, fmt.Errorf("el cursor tiene %d valores y se esperaban %d", len(valores), len(q.orden)+1)
+	}
+
+	for i, criterio := range q.orden {
+		var destino any
+		var precio string
+		switch criterio.campo {
+		case "id":
+			destino = &producto.ID
+		case "sku":
+			destino = &producto.SKU
+		case "nombre":
+			destino = &producto.Nombre
+		case "precio":
+			destino = &precio
+		case "stock":
+			destino = &producto.Stock
+		case "creado_en":
+			destino = &producto.CreadoEn
+		case "actualizado_en":
+			destino = &producto.ActualizadoEn
+		}
+		if err := json.Unmarshal(valores[i], destino); err != nil {
+			return producto, err
+		}
+		if criterio.campo == "precio" {
+			decimal, moneda, _ := strings.Cut(precio, " ")
+			if producto.Precio, err = dinero.Parsear(decimal, moneda); err != nil {
+				return producto, err
+			}
+		}
+	}
+	err = json.Unmarshal(valores[len(q.orden)], &producto.ID)
+	return producto, err
+}
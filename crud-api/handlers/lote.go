@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+
+	"crud-api/errores"
+	"crud-api/models"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxOperacionesLote limita el tamaño de un lote para acotar la memoria
+// y el tiempo que el repositorio queda bloqueado
+const maxOperacionesLote = 5000
+
+// peticionLote es el cuerpo de POST /productos/bulk
+type peticionLote struct {
+	// Atomico indica si se aplican todas las operaciones o ninguna
+	Atomico     bool            `json:"atomico"`
+	Operaciones []operacionLote `json:"operaciones"`
+}
+
+// operacionLote es una operación tal como llega en el JSON
+type operacionLote struct {
+	Op       string           `json:"op"`
+	ID       int              `json:"id"`
+	Version  int              `json:"version"`
+	Producto *models.Producto `json:"producto"`
+}
+
+// resultadoOperacion es el estado de una operación en la respuesta
+type resultadoOperacion struct {
+	Indice   int               `json:"indice"`
+	Status   int               `json:"status"`
+	Producto *models.Producto  `json:"producto,omitempty"`
+	Error    *errores.Problema `json:"error,omitempty"`
+}
+
+// fallar marca la operación con un error de la API
+func (r *resultadoOperacion) fallar(e *errores.Error) {
+	problema := e.Problema("")
+	r.Status = e.Status
+	r.Error = &problema
+}
+
+// ProcesarLote - POST /productos/bulk
+// Crea, actualiza y elimina varios productos en una sola petición.
+// Cada operación se valida con las mismas reglas que su endpoint individual.
+func (h *ProductoHandler) ProcesarLote(c *gin.Context) {
+	var peticion peticionLote
+	if err := c.ShouldBindJSON(&peticion); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	if len(peticion.Operaciones) == 0 || len(peticion.Operaciones) > maxOperacionesLote {
+		c.Error(errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "Cantidad de operaciones inválida").
+			ConDetalle("el lote debe tener entre 1 y %d operaciones", maxOperacionesLote))
+		return
+	}
+
+	// Validar todo antes de tocar el repositorio
+	resultados := make([]resultadoOperacion, len(peticion.Operaciones))
+	operaciones := make([]repository.OperacionLote, 0, len(peticion.Operaciones))
+	indices := make([]int, 0, len(peticion.Operaciones)) // posición original de cada operación válida
+	invalidas := 0
+
+	for i, operacion := range peticion.Operaciones {
+		resultados[i].Indice = i
+		if err := validarOperacionLote(operacion); err != nil {
+			resultados[i].fallar(err)
+			invalidas++
+			continue
+		}
+
+		valida := repository.OperacionLote{Op: operacion.Op, ID: operacion.ID, Version: operacion.Version}
+		if operacion.Producto != nil {
+			valida.Producto = *operacion.Producto
+		}
+		operaciones = append(operaciones, valida)
+		indices = append(indices, i)
+	}
+
+	// En modo atómico una operación inválida cancela el lote completo
+	if peticion.Atomico && invalidas > 0 {
+		for i := range resultados {
+			if resultados[i].Status == 0 {
+				resultados[i].fallar(errorRepositorio(repository.ErrLoteCancelado))
+			}
+		}
+		responderLote(c, peticion.Atomico, resultados)
+		return
+	}
+
+	aplicados, err := h.repo.Bulk(operaciones, peticion.Atomico)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+
+	for j, aplicado := range aplicados {
+		resultado := &resultados[indices[j]]
+		if aplicado.Err != nil {
+			resultado.fallar(errorRepositorio(aplicado.Err))
+			continue
+		}
+
+		switch operaciones[j].Op {
+		case repository.OpCrear:
+			resultado.Status = http.StatusCreated
+		default:
+			resultado.Status = http.StatusOK
+		}
+		if operaciones[j].Op == repository.OpEliminar {
+			h.reindexar(c, operaciones[j].ID)
+		} else {
+			producto := aplicado.Producto
+			resultado.Producto = &producto
+			h.reindexar(c, producto.ID)
+		}
+	}
+
+	responderLote(c, peticion.Atomico, resultados)
+}
+
+// validarOperacionLote revisa que una operación tenga los datos que necesita
+func validarOperacionLote(operacion operacionLote) *errores.Error {
+	invalida := func(formato string, args ...any) *errores.Error {
+		return errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "Operación inválida").
+			ConDetalle(formato, args...)
+	}
+
+	switch operacion.Op {
+	case repository.OpCrear:
+	case repository.OpActualizar, repository.OpEliminar:
+		if operacion.ID <= 0 {
+			return invalida("%s requiere un id válido", operacion.Op)
+		}
+	default:
+		return invalida("op debe ser create, update o delete")
+	}
+
+	if operacion.Op == repository.OpEliminar {
+		return nil
+	}
+	if operacion.Producto == nil {
+		return invalida("%s requiere producto", operacion.Op)
+	}
+	if err := binding.Validator.ValidateStruct(operacion.Producto); err != nil {
+		return errores.DesdeBinding(err)
+	}
+	return nil
+}
+
+// responderLote arma la respuesta con el estado de cada operación.
+// Un lote atómico que no se aplicó es un error: se responde problem+json
+// con los resultados como miembro adicional.
+func responderLote(c *gin.Context, atomico bool, resultados []resultadoOperacion) {
+	exitosas := 0
+	for _, resultado := range resultados {
+		if resultado.Error == nil {
+			exitosas++
+		}
+	}
+
+	if atomico && exitosas < len(resultados) {
+		c.Error(errores.Nuevo(http.StatusBadRequest, errores.CodigoLoteCancelado, "El lote no se aplicó porque una operación falló").
+			ConExtra("atomico", atomico).
+			ConExtra("resultados", resultados))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"atomico":    atomico,
+		"exitosas":   exitosas,
+		"fallidas":   len(resultados) - exitosas,
+		"resultados": resultados,
+	})
+}
@@ -1,139 +1,350 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/errores"
+	"crud-api/jsonpatch"
 	"crud-api/models"
+	"crud-api/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// ProductoHandler agrupa los handlers de productos, el repositorio que
+// usan, los tipos de cambio para ?currency= y el índice de búsqueda, que
+// actualizan con reindexar después de cada escritura
+type ProductoHandler struct {
+	repo    repository.ProductoRepository
+	cambios *cambio.Cambios
+	indice  *busqueda.Indice
+}
+
+// NuevoProductoHandler crea los handlers de productos sobre un repositorio
+func NuevoProductoHandler(repo repository.ProductoRepository, cambios *cambio.Cambios, indice *busqueda.Indice) *ProductoHandler {
+	return &ProductoHandler{repo: repo, cambios: cambios, indice: indice}
+}
+
 // ListarProductos - GET /productos
-// Retorna todos los productos
-func ListarProductos(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"productos": models.Productos,
-		"total":     len(models.Productos),
+// Retorna los productos paginados, con orden y filtros opcionales:
+// ?page=&limit= o ?after=<cursor>, ?sort=precio,-nombre,
+// ?precio_min=&precio_max=, ?categoria_id=, ?activo=, ?q= (búsqueda en el
+// nombre), ?currency= (precios en esa moneda; los filtros y el orden por
+// precio usan el precio convertido) e ?include_deleted=true (también los
+// de la papelera)
+func (h *ProductoHandler) ListarProductos(c *gin.Context) {
+	consulta, err := parsearConsulta(c)
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+
+	productos, err := h.repo.List()
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	h.responderListado(c, consulta, productos)
+}
+
+// responderListado responde la página pedida de los productos, con sus
+// precios en la moneda de la consulta
+func (h *ProductoHandler) responderListado(c *gin.Context, consulta consultaListado, productos []models.Producto) {
+	tabla := h.cambios.Tabla()
+	resultado, errMoneda := consulta.aplicar(productos, func(productos []models.Producto) ([]models.Producto, *errores.Error) {
+		return convertirConTabla(tabla, consulta.moneda, productos)
 	})
+	if errMoneda != nil {
+		c.Error(errMoneda)
+		return
+	}
+	respuesta := gin.H{
+		"productos": resultado.productos,
+		"total":     resultado.total,
+		"limite":    consulta.limite,
+		"links":     consulta.links(c, resultado),
+	}
+	if consulta.despuesDe == nil {
+		respuesta["pagina"] = consulta.pagina
+	}
+	if resultado.siguienteCursor != "" {
+		respuesta["siguiente_cursor"] = resultado.siguienteCursor
+	}
+
+	c.JSON(http.StatusOK, respuesta)
 }
 
 // ObtenerProducto - GET /productos/:id
-// Retorna un producto específico por ID
-func ObtenerProducto(c *gin.Context) {
+// Retorna un producto específico por ID; con ?currency= el precio va en
+// esa moneda. Los de la papelera solo con ?include_deleted=true.
+func (h *ProductoHandler) ObtenerProducto(c *gin.Context) {
 	// Obtener el ID de los parámetros de la URL
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "ID inválido",
-		})
+		c.Error(errIDInvalido())
+		return
+	}
+	moneda, err := monedaPedida(c)
+	if err != nil {
+		c.Error(errParametroInvalido(err))
+		return
+	}
+	incluirEliminados, err := parsearBooleano(c, "include_deleted")
+	if err != nil {
+		c.Error(errParametroInvalido(err))
 		return
 	}
 
 	// Buscar el producto
-	for _, producto := range models.Productos {
-		if producto.ID == id {
-			c.JSON(http.StatusOK, producto)
-			return
-		}
+	producto, err := h.repo.Get(id)
+	if err == nil && producto.EliminadoEn != nil && !incluirEliminados {
+		err = repository.ErrProductoNoEncontrado
+	}
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	convertidos, tabla, errMoneda := h.enMoneda(moneda, []models.Producto{producto})
+	if errMoneda != nil {
+		c.Error(errMoneda)
+		return
 	}
+	producto = convertidos[0]
 
-	// Si no se encuentra, retornar 404
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Producto no encontrado",
-	})
+	// Si el cliente ya tiene esta versión no hace falta reenviarla
+	etiqueta := etagEnMoneda(producto, tabla)
+	c.Header("ETag", etiqueta)
+	if coincideETag(c.GetHeader("If-None-Match"), etiqueta, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, producto)
 }
 
 // CrearProducto - POST /productos
 // Crea un nuevo producto
-func CrearProducto(c *gin.Context) {
+func (h *ProductoHandler) CrearProducto(c *gin.Context) {
 	var nuevoProducto models.Producto
 
 	// Bind JSON al struct y validar
 	if err := c.ShouldBindJSON(&nuevoProducto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(errores.DesdeBinding(err))
 		return
 	}
 
-	// Asignar ID automático
-	nuevoProducto.ID = models.SiguienteID
-	models.SiguienteID++
-
-	// Agregar a la lista
-	models.Productos = append(models.Productos, nuevoProducto)
+	// Guardar (el repositorio asigna el ID automático)
+	creado, err := h.repo.Create(nuevoProducto)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	h.reindexar(c, creado.ID)
 
 	// Retornar el producto creado con código 201
-	c.JSON(http.StatusCreated, nuevoProducto)
+	c.Header("ETag", etag(creado))
+	c.JSON(http.StatusCreated, creado)
 }
 
 // ActualizarProducto - PUT /productos/:id
 // Actualiza un producto existente
-func ActualizarProducto(c *gin.Context) {
+func (h *ProductoHandler) ActualizarProducto(c *gin.Context) {
 	// Obtener el ID de los parámetros
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "ID inválido",
-		})
+		c.Error(errIDInvalido())
 		return
 	}
 
 	// Bind del JSON
 	var productoActualizado models.Producto
 	if err := c.ShouldBindJSON(&productoActualizado); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(errores.DesdeBinding(err))
 		return
 	}
 
-	// Buscar y actualizar el producto
-	for i, producto := range models.Productos {
-		if producto.ID == id {
-			// Mantener el ID original
-			productoActualizado.ID = id
-			models.Productos[i] = productoActualizado
+	// Con If-Match solo se actualiza si nadie lo modificó antes
+	version, ok := h.versionEsperada(c, id)
+	if !ok {
+		return
+	}
+	productoActualizado.Version = version
 
-			c.JSON(http.StatusOK, productoActualizado)
-			return
-		}
+	// Actualizar manteniendo el ID original
+	actualizado, err := h.repo.Update(id, productoActualizado)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
 	}
+	h.reindexar(c, id)
 
-	// Si no se encuentra
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Producto no encontrado",
-	})
+	c.Header("ETag", etag(actualizado))
+	c.JSON(http.StatusOK, actualizado)
+}
+
+// ModificarProducto - PATCH /productos/:id
+// Actualiza solo los campos enviados. Acepta JSON Merge Patch (RFC 7396,
+// Content-Type application/merge-patch+json o application/json) y
+// JSON Patch (RFC 6902, Content-Type application/json-patch+json).
+// El resultado se valida con las mismas reglas que POST y PUT.
+func (h *ProductoHandler) ModificarProducto(c *gin.Context) {
+	// Obtener el ID de los parámetros
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	aplicar, patch, ok := leerPatch(c)
+	if !ok {
+		return
+	}
+
+	// Partir del producto actual; los de la papelera hay que restaurarlos
+	// antes de modificarlos
+	actual, err := h.repo.Get(id)
+	if err == nil && actual.EliminadoEn != nil {
+		err = repository.ErrProductoNoEncontrado
+	}
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	if header := c.GetHeader("If-Match"); header != "" && !coincideETag(header, etag(actual), false) {
+		responderPrecondicionFallida(c)
+		return
+	}
+	documento, err := json.Marshal(actual)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+
+	modificado, err := aplicar(documento, patch)
+	if err != nil {
+		c.Error(errorPatch(err, "El producto no coincide con la operación test"))
+		return
+	}
+
+	// Validar el resultado con las reglas del modelo
+	var productoActualizado models.Producto
+	if err := json.Unmarshal(modificado, &productoActualizado); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&productoActualizado); err != nil {
+		c.Error(errores.DesdeBinding(err))
+		return
+	}
+
+	// Guardar solo si no cambió desde que se leyó, para no pisar otra
+	// modificación hecha entre el Get y el Update
+	productoActualizado.Version = actual.Version
+	actualizado, err := h.repo.Update(id, productoActualizado)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	h.reindexar(c, id)
+
+	c.Header("ETag", etag(actualizado))
+	c.JSON(http.StatusOK, actualizado)
+}
+
+// maxTamanoPatch limita el cuerpo de un PATCH; es el mismo límite que
+// aplica openapi.ValidarCuerpo, pero el handler no depende de que corra
+const maxTamanoPatch = 16 << 20 // 16 MB
+
+// leerPatch elige el formato del parche según el Content-Type y lee el
+// cuerpo. Si el formato no es válido o el cuerpo pasa de maxTamanoPatch
+// (413) responde y retorna false.
+func leerPatch(c *gin.Context) (func(doc, patch []byte) ([]byte, error), []byte, bool) {
+	tipo, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	var aplicar func(doc, patch []byte) ([]byte, error)
+	switch tipo {
+	case "application/merge-patch+json", "application/json":
+		aplicar = jsonpatch.AplicarMergePatch
+	case "application/json-patch+json":
+		aplicar = jsonpatch.AplicarJSONPatch
+	default:
+		c.Error(errores.Nuevo(http.StatusUnsupportedMediaType, errores.CodigoTipoNoSoportado, "Tipo de contenido no soportado").
+			ConDetalle("Content-Type debe ser application/merge-patch+json o application/json-patch+json"))
+		return nil, nil, false
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTamanoPatch))
+	if err != nil {
+		// DesdeBinding responde 413 si se pasó del límite
+		c.Error(errores.DesdeBinding(err))
+		return nil, nil, false
+	}
+	return aplicar, patch, true
+}
+
+// errorPatch traduce el error al aplicar un parche; tituloPrueba es el
+// mensaje cuando falla una operación test
+func errorPatch(err error, tituloPrueba string) *errores.Error {
+	if errors.Is(err, jsonpatch.ErrPruebaFallida) {
+		return errores.Nuevo(http.StatusConflict, errores.CodigoConflicto, tituloPrueba).ConDetalle("%v", err)
+	}
+	return errores.Nuevo(http.StatusBadRequest, errores.CodigoPatchInvalido, "El parche no es válido").ConDetalle("%v", err)
 }
 
 // EliminarProducto - DELETE /productos/:id
-// Elimina un producto
-func EliminarProducto(c *gin.Context) {
+// Envía un producto a la papelera; se puede restaurar hasta que se purgue
+func (h *ProductoHandler) EliminarProducto(c *gin.Context) {
 	// Obtener el ID
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "ID inválido",
-		})
+		c.Error(errIDInvalido())
 		return
 	}
 
-	// Buscar y eliminar el producto
-	for i, producto := range models.Productos {
-		if producto.ID == id {
-			// Eliminar del slice
-			models.Productos = append(models.Productos[:i], models.Productos[i+1:]...)
+	version, ok := h.versionEsperada(c, id)
+	if !ok {
+		return
+	}
 
-			c.JSON(http.StatusOK, gin.H{
-				"mensaje": "Producto eliminado exitosamente",
-			})
-			return
-		}
+	if err := h.repo.Delete(id, version); err != nil {
+		c.Error(errorRepositorio(err))
+		return
 	}
+	h.reindexar(c, id)
 
-	// Si no se encuentra
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Producto no encontrado",
+	c.JSON(http.StatusOK, gin.H{
+		"mensaje": "Producto eliminado exitosamente",
 	})
 }
+
+// RestaurarProducto - POST /productos/:id/restore
+// Saca un producto de la papelera
+func (h *ProductoHandler) RestaurarProducto(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errIDInvalido())
+		return
+	}
+
+	version, ok := h.versionEsperada(c, id)
+	if !ok {
+		return
+	}
+
+	restaurado, err := h.repo.Restore(id, version)
+	if err != nil {
+		c.Error(errorRepositorio(err))
+		return
+	}
+	h.reindexar(c, id)
+
+	c.Header("ETag", etag(restaurado))
+	c.JSON(http.StatusOK, restaurado)
+}
@@ -0,0 +1,43 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/errores"
+	"crud-api/handlers"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPatchLimitaElCuerpo llama al handler sin el middleware de
+// validación: el límite de tamaño no depende de que corra
+func TestPatchLimitaElCuerpo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cambios, err := cambio.Abrir("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cambios.Close()
+
+	router := gin.New()
+	router.Use(errores.Middleware())
+	productos := handlers.NuevoProductoHandler(repository.NuevoMemoriaRepository(), cambios, busqueda.NuevoIndice())
+	router.PATCH("/productos/:id", productos.ModificarProducto)
+
+	grande := `{"descripcion":"` + strings.Repeat("a", 17<<20) + `"}`
+	for _, tipo := range []string{"application/json", "application/merge-patch+json", "application/json-patch+json"} {
+		peticion := httptest.NewRequest(http.MethodPatch, "/productos/1", strings.NewReader(grande))
+		peticion.Header.Set("Content-Type", tipo)
+		respuesta := httptest.NewRecorder()
+		router.ServeHTTP(respuesta, peticion)
+		if respuesta.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s de 17 MB: status %d, se esperaba 413", tipo, respuesta.Code)
+		}
+	}
+}
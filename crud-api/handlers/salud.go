@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"crud-api/errores"
+	"crud-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaludHandler responde las sondas del balanceador
+type SaludHandler struct {
+	repo repository.ProductoRepository
+}
+
+// NuevoSaludHandler crea los handlers de salud sobre un repositorio
+func NuevoSaludHandler(repo repository.ProductoRepository) *SaludHandler {
+	return &SaludHandler{repo: repo}
+}
+
+// Vivo - GET /healthz
+// Responde mientras el proceso esté atendiendo peticiones; no revisa
+// dependencias, así un almacenamiento caído no hace reiniciar el proceso
+func (h *SaludHandler) Vivo(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"estado": "ok"})
+}
+
+// Listo - GET /readyz
+// Responde 200 si el almacenamiento puede atender operaciones y 503 si
+// no, para que el balanceador deje de enviar tráfico
+func (h *SaludHandler) Listo(c *gin.Context) {
+	if err := h.repo.Ping(); err != nil {
+		e := errores.Nuevo(http.StatusServiceUnavailable, errores.CodigoNoDisponible, "El almacenamiento no está disponible").
+			ConExtra("estado", "no_listo")
+		e.Causa = err
+		c.Error(e)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"estado": "listo"})
+}
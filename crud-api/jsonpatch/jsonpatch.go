@@ -0,0 +1,336 @@
+// Package jsonpatch aplica parches sobre documentos JSON:
+// JSON Merge Patch (RFC 7396) y JSON Patch (RFC 6902).
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrPruebaFallida se retorna cuando una operación "test" no coincide
+var ErrPruebaFallida = errors.New("la operación test no coincide")
+
+// AplicarMergePatch aplica un JSON Merge Patch (RFC 7396) sobre doc.
+// Los campos con null se eliminan y los objetos se combinan recursivamente;
+// cualquier otro valor reemplaza al original.
+func AplicarMergePatch(doc, patch []byte) ([]byte, error) {
+	var original, cambios any
+	if err := json.Unmarshal(doc, &original); err != nil {
+		return nil, fmt.Errorf("documento inválido: %w", err)
+	}
+	if err := json.Unmarshal(patch, &cambios); err != nil {
+		return nil, fmt.Errorf("merge patch inválido: %w", err)
+	}
+
+	return json.Marshal(combinar(original, cambios))
+}
+
+// combinar implementa el algoritmo MergePatch de la RFC 7396
+func combinar(destino, patch any) any {
+	cambios, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	objeto, ok := destino.(map[string]any)
+	if !ok {
+		objeto = map[string]any{}
+	}
+	for clave, valor := range cambios {
+		if valor == nil {
+			delete(objeto, clave)
+			continue
+		}
+		objeto[clave] = combinar(objeto[clave], valor)
+	}
+	return objeto
+}
+
+// Operacion es una entrada de un documento JSON Patch
+type Operacion struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// AplicarJSONPatch aplica un JSON Patch (RFC 6902) sobre doc. Las
+// operaciones se aplican en orden y si alguna falla no se aplica ninguna.
+func AplicarJSONPatch(doc, patch []byte) ([]byte, error) {
+	var documento any
+	if err := json.Unmarshal(doc, &documento); err != nil {
+		return nil, fmt.Errorf("documento inválido: %w", err)
+	}
+
+	var operaciones []Operacion
+	if err := json.Unmarshal(patch, &operaciones); err != nil {
+		return nil, fmt.Errorf("JSON patch inválido: %w", err)
+	}
+
+	for i, operacion := range operaciones {
+		var err error
+		documento, err = aplicarOperacion(documento, operacion)
+		if err != nil {
+			return nil, fmt.Errorf("operación %d (%s %s): %w", i, operacion.Op, operacion.Path, err)
+		}
+	}
+
+	return json.Marshal(documento)
+}
+
+// aplicarOperacion ejecuta una operación y retorna el documento resultante
+func aplicarOperacion(documento any, operacion Operacion) (any, error) {
+	ruta, err := parsearPuntero(operacion.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	valor := func() (any, error) {
+		if len(operacion.Value) == 0 {
+			return nil, errors.New("falta value")
+		}
+		var v any
+		err := json.Unmarshal(operacion.Value, &v)
+		return v, err
+	}
+
+	switch operacion.Op {
+	case "add":
+		v, err := valor()
+		if err != nil {
+			return nil, err
+		}
+		return agregar(documento, ruta, v)
+
+	case "remove":
+		documento, _, err := quitar(documento, ruta)
+		return documento, err
+
+	case "replace":
+		v, err := valor()
+		if err != nil {
+			return nil, err
+		}
+		if documento, _, err = quitar(documento, ruta); err != nil {
+			return nil, err
+		}
+		return agregar(documento, ruta, v)
+
+	case "move":
+		origen, err := parsearPuntero(operacion.From)
+		if err != nil {
+			return nil, err
+		}
+		if esPrefijo(origen, ruta) && len(origen) < len(ruta) {
+			return nil, errors.New("no se puede mover un valor dentro de sí mismo")
+		}
+		documento, v, err := quitar(documento, origen)
+		if err != nil {
+			return nil, err
+		}
+		return agregar(documento, ruta, v)
+
+	case "copy":
+		origen, err := parsearPuntero(operacion.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := obtener(documento, origen)
+		if err != nil {
+			return nil, err
+		}
+		return agregar(documento, ruta, copiarProfundo(v))
+
+	case "test":
+		esperado, err := valor()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := obtener(documento, ruta)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, esperado) {
+			return nil, ErrPruebaFallida
+		}
+		return documento, nil
+	}
+
+	return nil, fmt.Errorf("operación desconocida %q", operacion.Op)
+}
+
+// parsearPuntero convierte un JSON Pointer (RFC 6901) en sus segmentos
+func parsearPuntero(puntero string) ([]string, error) {
+	if puntero == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(puntero, "/") {
+		return nil, fmt.Errorf("puntero inválido %q", puntero)
+	}
+
+	segmentos := strings.Split(puntero[1:], "/")
+	for i, segmento := range segmentos {
+		segmento = strings.ReplaceAll(segmento, "~1", "/")
+		segmentos[i] = strings.ReplaceAll(segmento, "~0", "~")
+	}
+	return segmentos, nil
+}
+
+func esPrefijo(prefijo, ruta []string) bool {
+	if len(prefijo) > len(ruta) {
+		return false
+	}
+	for i := range prefijo {
+		if prefijo[i] != ruta[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// obtener retorna el valor en la ruta indicada
+func obtener(documento any, ruta []string) (any, error) {
+	actual := documento
+	for _, segmento := range ruta {
+		switch nodo := actual.(type) {
+		case map[string]any:
+			v, ok := nodo[segmento]
+			if !ok {
+				return nil, fmt.Errorf("no existe %q", segmento)
+			}
+			actual = v
+		case []any:
+			i, err := indiceArray(segmento, len(nodo)-1)
+			if err != nil {
+				return nil, err
+			}
+			actual = nodo[i]
+		default:
+			return nil, fmt.Errorf("no existe %q", segmento)
+		}
+	}
+	return actual, nil
+}
+
+// agregar inserta v en la ruta; en un objeto reemplaza la clave y en un
+// array inserta en la posición ("-" agrega al final)
+func agregar(documento any, ruta []string, v any) (any, error) {
+	if len(ruta) == 0 {
+		return v, nil
+	}
+
+	padre, err := obtener(documento, ruta[:len(ruta)-1])
+	if err != nil {
+		return nil, err
+	}
+	ultimo := ruta[len(ruta)-1]
+
+	switch nodo := padre.(type) {
+	case map[string]any:
+		nodo[ultimo] = v
+	case []any:
+		i := len(nodo)
+		if ultimo != "-" {
+			if i, err = indiceArray(ultimo, len(nodo)); err != nil {
+				return nil, err
+			}
+		}
+		nodo = append(nodo, nil)
+		copy(nodo[i+1:], nodo[i:])
+		nodo[i] = v
+		return reemplazar(documento, ruta[:len(ruta)-1], nodo)
+	default:
+		return nil, fmt.Errorf("no se puede agregar en %q", ultimo)
+	}
+	return documento, nil
+}
+
+// quitar elimina el valor en la ruta y lo retorna
+func quitar(documento any, ruta []string) (any, any, error) {
+	if len(ruta) == 0 {
+		return nil, documento, nil
+	}
+
+	padre, err := obtener(documento, ruta[:len(ruta)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	ultimo := ruta[len(ruta)-1]
+
+	switch nodo := padre.(type) {
+	case map[string]any:
+		v, ok := nodo[ultimo]
+		if !ok {
+			return nil, nil, fmt.Errorf("no existe %q", ultimo)
+		}
+		delete(nodo, ultimo)
+		return documento, v, nil
+	case []any:
+		i, err := indiceArray(ultimo, len(nodo)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := nodo[i]
+		nodo = append(nodo[:i], nodo[i+1:]...)
+		documento, err = reemplazar(documento, ruta[:len(ruta)-1], nodo)
+		return documento, v, err
+	}
+	return nil, nil, fmt.Errorf("no existe %q", ultimo)
+}
+
+// reemplazar asigna v en la ruta (usado cuando un array cambia de tamaño)
+func reemplazar(documento any, ruta []string, v any) (any, error) {
+	if len(ruta) == 0 {
+		return v, nil
+	}
+
+	padre, err := obtener(documento, ruta[:len(ruta)-1])
+	if err != nil {
+		return nil, err
+	}
+	ultimo := ruta[len(ruta)-1]
+
+	switch nodo := padre.(type) {
+	case map[string]any:
+		nodo[ultimo] = v
+	case []any:
+		i, err := indiceArray(ultimo, len(nodo)-1)
+		if err != nil {
+			return nil, err
+		}
+		nodo[i] = v
+	}
+	return documento, nil
+}
+
+// indiceArray valida un segmento numérico entre 0 y maximo
+func indiceArray(segmento string, maximo int) (int, error) {
+	i, err := strconv.Atoi(segmento)
+	if err != nil || i < 0 || i > maximo || (len(segmento) > 1 && segmento[0] == '0') {
+		return 0, fmt.Errorf("índice de array inválido %q", segmento)
+	}
+	return i, nil
+}
+
+// copiarProfundo duplica objetos y arrays para que "copy" no comparta datos
+func copiarProfundo(v any) any {
+	switch nodo := v.(type) {
+	case map[string]any:
+		copia := make(map[string]any, len(nodo))
+		for clave, valor := range nodo {
+			copia[clave] = copiarProfundo(valor)
+		}
+		return copia
+	case []any:
+		copia := make([]any, len(nodo))
+		for i, valor := range nodo {
+			copia[i] = copiarProfundo(valor)
+		}
+		return copia
+	}
+	return v
+}
@@ -1,24 +1,303 @@
 package main
 
 import (
+	"context"
+	"crud-api/auth"
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/config"
+	"crud-api/cors"
+	"crud-api/errores"
+	"crud-api/metricas"
+	"crud-api/openapi"
+	"crud-api/ratelimit"
+	"crud-api/registro"
+	"crud-api/repository"
 	"crud-api/routes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Crear el router de Gin
-	router := gin.Default()
+	// Configuración: valores por defecto, archivo YAML, entorno y flags
+	cfg, err := config.Cargar(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Logs estructurados en stderr; lo que aún use el paquete log sale como info
+	slog.SetDefault(slog.New(cfg.Log.Handler(os.Stderr)))
+	gin.SetMode(cfg.Servidor.Modo)
+
+	// SIGINT (Ctrl+C) y SIGTERM inician el apagado ordenado
+	ctx, detener := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer detener()
+
+	if err := ejecutar(ctx, cfg); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	slog.Info("servidor detenido")
+}
+
+// ejecutar abre el almacenamiento, sirve la API hasta que ctx se cancela y
+// luego espera las peticiones en curso y cierra el almacenamiento. Los
+// errores se retornan en lugar de terminar el proceso para que siempre se
+// cierre lo que se abrió.
+func ejecutar(ctx context.Context, cfg config.Config) (err error) {
+	repo, err := abrirRepositorio(cfg.Almacenamiento)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Vuelca a disco lo pendiente (el backend archivo compacta el log)
+		if cerrable, ok := repo.(io.Closer); ok {
+			if errCerrar := cerrable.Close(); errCerrar != nil {
+				err = errors.Join(err, fmt.Errorf("error al cerrar el almacenamiento: %w", errCerrar))
+			}
+		}
+	}()
+
+	// Índice de búsqueda de texto; se arma con el catálogo actual y los
+	// handlers lo mantienen al día
+	indice, err := busqueda.Indexar(repo.Each)
+	if err != nil {
+		return fmt.Errorf("error al indexar los productos: %w", err)
+	}
+
+	autenticacion, err := nuevaAutenticacion(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("error al configurar la autenticación: %w", err)
+	}
+	defer func() {
+		if errCerrar := autenticacion.Cerrar(); errCerrar != nil {
+			err = errors.Join(err, fmt.Errorf("error al guardar las API keys: %w", errCerrar))
+		}
+	}()
+
+	// Tipos de cambio para ?currency=; SIGHUP vuelve a leer el archivo
+	cambios, err := cambio.Abrir(cfg.Cambio.Archivo, cfg.Cambio.Recarga)
+	if err != nil {
+		return err
+	}
+	defer cambios.Close()
+	go recargarConSIGHUP(ctx, cambios)
+
+	// Los productos eliminados se borran definitivamente pasada la
+	// retención. Al salir, después de esperar las peticiones en curso, se
+	// detiene la purga y se espera a que termine antes de cerrar el
+	// almacenamiento.
+	ctxPurga, detenerPurga := context.WithCancel(ctx)
+	purgaTerminada := make(chan struct{})
+	go func() {
+		defer close(purgaTerminada)
+		purgarPapelera(ctxPurga, repo, cfg.Papelera)
+	}()
+	defer func() {
+		detenerPurga()
+		<-purgaTerminada
+	}()
+
+	// Crear el router de Gin; los panics también se responden como problem+json.
+	// Las métricas y el registro van antes del recovery para ver también
+	// las respuestas de los panics.
+	m := metricas.Nuevas()
+	router := gin.New()
+	router.Use(m.Middleware(), registro.Middleware(), gin.CustomRecovery(errores.Recuperar), cors.Middleware(cfg.CORS.Origenes))
+
+	// La IP del cliente identifica a quien se limita: sin proxies confiables
+	// se usa la de la conexión y se ignora X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.Servidor.ProxiesConfiables); err != nil {
+		return fmt.Errorf("proxies confiables inválidos: %w", err)
+	}
 
 	// Configurar las rutas
-	routes.SetupRoutes(router)
+	routes.SetupRoutes(router, repo, autenticacion, routes.Limites{
+		Lectura:   limite(cfg.Limites.Lectura),
+		Escritura: limite(cfg.Limites.Escritura),
+		Auth:      limite(cfg.Limites.Auth),
+	}, m, cambios, indice)
+
+	// Una ruta sin documentar en openapi.json impide iniciar, así el
+	// documento no se atrasa respecto del código
+	if err := openapi.Verificar(router.Routes()); err != nil {
+		return err
+	}
 
-	// Mensaje de inicio
-	log.Println("Servidor iniciado en http://localhost:8080")
+	servidor := &http.Server{
+		Addr:              cfg.Servidor.Direccion,
+		Handler:           router,
+		ReadTimeout:       cfg.Servidor.Timeouts.Lectura,
+		ReadHeaderTimeout: cfg.Servidor.Timeouts.LecturaEncabezados,
+		WriteTimeout:      cfg.Servidor.Timeouts.Escritura,
+		IdleTimeout:       cfg.Servidor.Timeouts.Inactividad,
+	}
+
+	// Iniciar el servidor, con TLS si hay certificado
+	esquema := "http"
+	if cfg.Servidor.TLS.Habilitado() {
+		esquema = "https"
+	}
+	slog.Info("servidor iniciado", "url", esquema+"://"+cfg.Servidor.Direccion, "almacenamiento", cfg.Almacenamiento.Backend)
+
+	errServidor := make(chan error, 1)
+	go func() {
+		if cfg.Servidor.TLS.Habilitado() {
+			errServidor <- servidor.ListenAndServeTLS(cfg.Servidor.TLS.Certificado, cfg.Servidor.TLS.Clave)
+		} else {
+			errServidor <- servidor.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errServidor:
+		// No llegó a escuchar (puerto ocupado, certificado inválido, ...)
+		return fmt.Errorf("error al iniciar el servidor: %w", err)
+	case <-ctx.Done():
+	}
 
-	// Iniciar el servidor
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Error al iniciar el servidor:", err)
+	// Dejar de aceptar conexiones y esperar las peticiones en curso
+	slog.Info("apagando el servidor; se esperan las peticiones en curso", "plazo", cfg.Servidor.Timeouts.Apagado.String())
+	ctxApagado, cancelar := context.WithTimeout(context.Background(), cfg.Servidor.Timeouts.Apagado)
+	defer cancelar()
+	if err := servidor.Shutdown(ctxApagado); err != nil {
+		// Se acabó el plazo: cortar las conexiones que quedan
+		slog.Warn("plazo de apagado vencido; se cortan las peticiones en curso", "error", err)
+		servidor.Close()
+	}
+	return nil
+}
+
+// abrirRepositorio crea el almacenamiento de productos y categorías elegido
+func abrirRepositorio(cfg config.Almacenamiento) (repository.Repositorio, error) {
+	switch cfg.Backend {
+	case "sqlite":
+		repo, err := repository.NuevoSQLiteRepository(cfg.SQLite)
+		if err != nil {
+			return nil, fmt.Errorf("error al abrir la base de datos: %w", err)
+		}
+		return repo, nil
+	case "archivo":
+		repo, err := repository.NuevoArchivoRepository(cfg.Directorio, cfg.Compactacion)
+		if err != nil {
+			return nil, fmt.Errorf("error al abrir el almacenamiento en archivo: %w", err)
+		}
+		return repo, nil
+	}
+	// En memoria por defecto
+	return repository.NuevoMemoriaRepository(), nil
+}
+
+// recargarConSIGHUP recarga los tipos de cambio cada vez que el proceso
+// recibe SIGHUP, hasta que ctx se cancela
+func recargarConSIGHUP(ctx context.Context, cambios *cambio.Cambios) {
+	senales := make(chan os.Signal, 1)
+	signal.Notify(senales, syscall.SIGHUP)
+	defer signal.Stop(senales)
+
+	for {
+		select {
+		case <-senales:
+			tabla, err := cambios.Recargar()
+			switch {
+			case errors.Is(err, cambio.ErrSinArchivo):
+				slog.Warn("SIGHUP recibido, pero no hay archivo de tipos de cambio configurado")
+			case err != nil:
+				slog.Error("error al recargar los tipos de cambio; se sigue usando la tabla anterior", "error", err)
+			default:
+				slog.Info("tipos de cambio recargados", "fecha", tabla.Fecha, "huella", tabla.Huella)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// purgarPapelera borra definitivamente los productos que llevan en la
+// papelera más que la retención, al iniciar y luego cada intervalo, hasta
+// que ctx se cancela
+func purgarPapelera(ctx context.Context, repo repository.ProductoRepository, cfg config.Papelera) {
+	if cfg.Retencion == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Intervalo)
+	defer ticker.Stop()
+	for {
+		purgados, err := repo.Purge(time.Now().UTC().Add(-cfg.Retencion))
+		if err != nil {
+			slog.Error("error al purgar la papelera", "error", err)
+		} else if purgados > 0 {
+			slog.Info("papelera purgada", "productos", purgados)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// limite traduce la configuración de un grupo de rutas al limitador
+func limite(l config.Limite) ratelimit.Config {
+	return ratelimit.Config{Tasa: l.Tasa, Rafaga: l.Rafaga}
+}
+
+// nuevaAutenticacion arma el servicio de autenticación: carga los usuarios
+// y las API keys, agrega el administrador de CRUD_ADMIN_USUARIO/
+// CRUD_ADMIN_PASSWORD si está definido y prepara la firma de tokens
+func nuevaAutenticacion(cfg config.Auth) (*auth.Servicio, error) {
+	usuarios, err := auth.CargarUsuarios(cfg.Usuarios)
+	if err != nil {
+		return nil, err
+	}
+	if nombre := os.Getenv("CRUD_ADMIN_USUARIO"); nombre != "" {
+		password := os.Getenv("CRUD_ADMIN_PASSWORD")
+		if password == "" {
+			return nil, errors.New("CRUD_ADMIN_PASSWORD es obligatorio si se define CRUD_ADMIN_USUARIO")
+		}
+		if err := usuarios.Agregar(nombre, password, auth.RolAdmin); err != nil {
+			return nil, err
+		}
+	}
+	if usuarios.Cantidad() == 0 {
+		slog.Warn("no hay usuarios configurados; nadie podrá modificar productos")
+	}
+
+	tokensCfg := auth.ConfigTokens{Algoritmo: cfg.Algoritmo, Secreto: []byte(cfg.Secreto), Duracion: cfg.Duracion}
+	if cfg.ClavePrivada != "" {
+		if tokensCfg.ClavePrivada, err = auth.CargarClavePrivadaRSA(cfg.ClavePrivada); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Algoritmo == auth.AlgHS256 && cfg.Secreto == "" {
+		// Sin secreto fijo los tokens dejan de valer al reiniciar
+		slog.Warn("CRUD_JWT_SECRET no está definido; se usa un secreto aleatorio")
+		tokensCfg.Secreto = auth.SecretoAleatorio()
+	}
+
+	tokens, err := auth.NuevoTokens(tokensCfg)
+	if err != nil {
+		return nil, err
+	}
+	claves, err := auth.CargarAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
 	}
+	return auth.NuevoServicio(usuarios, tokens, claves), nil
 }
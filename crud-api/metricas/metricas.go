@@ -0,0 +1,204 @@
+// Package metricas cuenta las peticiones HTTP y las expone en el formato
+// de texto de Prometheus, sin dependencias externas.
+package metricas
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"crud-api/registro"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType es el formato de texto de Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// rutaDesconocida agrupa las peticiones que no coinciden con ninguna ruta,
+// así una URL inventada no crea una serie nueva
+const rutaDesconocida = "sin_ruta"
+
+// limites son los límites superiores (en segundos) de los buckets del
+// histograma de latencia
+var limites = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// serie identifica una combinación de etiquetas
+type serie struct {
+	metodo string
+	ruta   string
+	status int
+}
+
+// histograma acumula la latencia de una ruta
+type histograma struct {
+	buckets []uint64 // uno por límite, sin acumular
+	suma    float64
+	total   uint64
+}
+
+// Contador es lo que se necesita del almacenamiento para exponer la
+// cantidad de productos
+type Contador interface {
+	Count() (int, error)
+}
+
+// Metricas guarda los contadores del proceso
+type Metricas struct {
+	enCurso atomic.Int64
+
+	mu         sync.Mutex
+	peticiones map[serie]uint64
+	latencias  map[serie]*histograma // status siempre 0
+}
+
+// Nuevas crea las métricas vacías
+func Nuevas() *Metricas {
+	return &Metricas{
+		peticiones: make(map[serie]uint64),
+		latencias:  make(map[serie]*histograma),
+	}
+}
+
+// Middleware mide cada petición. La ruta es el patrón registrado (por
+// ejemplo /productos/:id), no la URL, para que la cantidad de series no
+// crezca con cada ID. Debe ir antes que el recovery para ver los 500.
+func (m *Metricas) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.enCurso.Add(1)
+		inicio := time.Now()
+
+		c.Next()
+
+		m.enCurso.Add(-1)
+		ruta := c.FullPath()
+		if ruta == "" {
+			ruta = rutaDesconocida
+		}
+		m.registrar(metodo(c.Request.Method), ruta, c.Writer.Status(), time.Since(inicio))
+	}
+}
+
+// metodo agrupa los métodos no estándar, por el mismo motivo que
+// rutaDesconocida
+func metodo(nombre string) string {
+	switch nombre {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return nombre
+	}
+	return "OTRO"
+}
+
+// registrar suma una petición terminada
+func (m *Metricas) registrar(metodo, ruta string, status int, duracion time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.peticiones[serie{metodo: metodo, ruta: ruta, status: status}]++
+
+	clave := serie{metodo: metodo, ruta: ruta}
+	h, ok := m.latencias[clave]
+	if !ok {
+		h = &histograma{buckets: make([]uint64, len(limites))}
+		m.latencias[clave] = h
+	}
+	segundos := duracion.Seconds()
+	for i, limite := range limites {
+		if segundos <= limite {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.suma += segundos
+	h.total++
+}
+
+// Handler - GET /metrics
+// Expone las métricas y la cantidad actual de productos
+func (m *Metricas) Handler(productos Contador) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", ContentType)
+		c.Status(http.StatusOK)
+
+		w := bufio.NewWriter(c.Writer)
+		m.escribir(w)
+
+		// Si el almacenamiento falla se omite la métrica; /readyz lo reporta
+		if cantidad, err := productos.Count(); err == nil {
+			encabezado(w, "crud_api_productos", "gauge", "Cantidad de productos almacenados.")
+			fmt.Fprintf(w, "crud_api_productos %d\n", cantidad)
+		} else {
+			registro.Logger(c).Error("error al contar productos para /metrics", "error", err)
+		}
+
+		w.Flush()
+	}
+}
+
+// escribir vuelca los contadores con las series ordenadas, así dos
+// lecturas seguidas son comparables
+func (m *Metricas) escribir(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	encabezado(w, "crud_api_http_requests_total", "counter", "Peticiones HTTP atendidas, por método, ruta y status.")
+	for _, s := range ordenar(m.peticiones) {
+		fmt.Fprintf(w, "crud_api_http_requests_total{method=%s,route=%s,status=\"%d\"} %d\n",
+			etiqueta(s.metodo), etiqueta(s.ruta), s.status, m.peticiones[s])
+	}
+
+	encabezado(w, "crud_api_http_request_duration_seconds", "histogram", "Latencia de las peticiones HTTP, por método y ruta.")
+	for _, s := range ordenar(m.latencias) {
+		h := m.latencias[s]
+		etiquetas := fmt.Sprintf("method=%s,route=%s", etiqueta(s.metodo), etiqueta(s.ruta))
+		var acumulado uint64
+		for i, limite := range limites {
+			acumulado += h.buckets[i]
+			fmt.Fprintf(w, "crud_api_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				etiquetas, strconv.FormatFloat(limite, 'g', -1, 64), acumulado)
+		}
+		fmt.Fprintf(w, "crud_api_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", etiquetas, h.total)
+		fmt.Fprintf(w, "crud_api_http_request_duration_seconds_sum{%s} %s\n", etiquetas, strconv.FormatFloat(h.suma, 'g', -1, 64))
+		fmt.Fprintf(w, "crud_api_http_request_duration_seconds_count{%s} %d\n", etiquetas, h.total)
+	}
+
+	encabezado(w, "crud_api_http_requests_in_flight", "gauge", "Peticiones HTTP en curso.")
+	fmt.Fprintf(w, "crud_api_http_requests_in_flight %d\n", m.enCurso.Load())
+}
+
+// encabezado escribe las líneas HELP y TYPE de una métrica
+func encabezado(w *bufio.Writer, nombre, tipo, ayuda string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", nombre, ayuda, nombre, tipo)
+}
+
+// etiqueta escapa un valor de etiqueta y lo pone entre comillas
+func etiqueta(valor string) string {
+	reemplazo := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + reemplazo.Replace(valor) + `"`
+}
+
+// ordenar retorna las series de un mapa por ruta, método y status
+func ordenar[V any](series map[serie]V) []serie {
+	ordenadas := make([]serie, 0, len(series))
+	for s := range series {
+		ordenadas = append(ordenadas, s)
+	}
+	sort.Slice(ordenadas, func(i, j int) bool {
+		a, b := ordenadas[i], ordenadas[j]
+		if a.ruta != b.ruta {
+			return a.ruta < b.ruta
+		}
+		if a.metodo != b.metodo {
+			return a.metodo < b.metodo
+		}
+		return a.status < b.status
+	})
+	return ordenadas
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Categoria es un nodo de la taxonomía de productos. Las categorías sin
+// padre son raíces; cada una puede tener subcategorías.
+type Categoria struct {
+	ID     int    `json:"id"`
+	Nombre string `json:"nombre" binding:"required,max=100"`
+	// PadreID es la categoría que la contiene, 0 si es una raíz
+	PadreID int `json:"padre_id,omitempty" binding:"gte=0"`
+	// Ruta son los nombres desde la raíz hasta esta categoría inclusive.
+	// Se calcula al responder, así renombrar o mover una categoría cambia
+	// también la ruta de sus subcategorías; no se guarda.
+	Ruta []string `json:"ruta,omitempty"`
+	// CreadoEn y ActualizadoEn los asigna el repositorio
+	CreadoEn      time.Time `json:"creado_en"`
+	ActualizadoEn time.Time `json:"actualizado_en"`
+	// Version aumenta en cada modificación; se expone como ETag
+	Version int `json:"version"`
+}
@@ -1,12 +1,205 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"crud-api/dinero"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// MonedaPorDefecto es la moneda de los productos que no indican una
+const MonedaPorDefecto = "USD"
+
 // Producto representa un producto en nuestro sistema
 type Producto struct {
-	ID     int     `json:"id"`
-	Nombre string  `json:"nombre" binding:"required"`
-	Precio float64 `json:"precio" binding:"required,gt=0"`
+	ID int `json:"id"`
+	// SKU es el código de inventario; si se indica no puede repetirse
+	SKU         string `json:"sku" binding:"max=64"`
+	Nombre      string `json:"nombre" binding:"required,max=200"`
+	Descripcion string `json:"descripcion" binding:"max=2000"`
+	// Categoria es una etiqueta libre obsoleta: se conserva para no perder
+	// los datos de antes de la taxonomía, pero no filtra ni agrupa nada.
+	// La categoría de un producto es CategoriaID.
+	Categoria string `json:"categoria" binding:"max=100"`
+	// CategoriaID ubica el producto en la taxonomía de categorías; 0 si no
+	// está en ninguna
+	CategoriaID int `json:"categoria_id,omitempty" binding:"gte=0"`
+	// Precio es exacto y lleva su moneda; en JSON se escribe como texto
+	// decimal ("899.90") y la moneda va aparte, en "moneda"
+	Precio dinero.Dinero `json:"precio" binding:"required,gt=0"`
+	// Precios son precios fijados en otras monedas; con ?currency= tienen
+	// prioridad sobre la conversión con el tipo de cambio
+	Precios Precios `json:"precios,omitempty"`
+	Stock   int     `json:"stock" binding:"gte=0"`
+	Activo  bool    `json:"activo"`
+	// CreadoEn y ActualizadoEn los asigna el repositorio
+	CreadoEn      time.Time `json:"creado_en"`
+	ActualizadoEn time.Time `json:"actualizado_en"`
+	// Version aumenta en cada modificación; se expone como ETag
+	Version int `json:"version"`
+	// EliminadoEn es cuándo se envió el producto a la papelera; nil si no
+	// está eliminado. Lo asigna el repositorio.
+	EliminadoEn *time.Time `json:"eliminado_en,omitempty"`
+	// Conversion solo está en las respuestas con ?currency= cuyo precio se
+	// convirtió; no se guarda
+	Conversion *Conversion `json:"conversion,omitempty"`
+}
+
+// Conversion describe cómo se obtuvo un precio convertido a otra moneda
+type Conversion struct {
+	// PrecioOriginal es el precio guardado, en su moneda
+	PrecioOriginal dinero.Dinero `json:"precio_original"`
+	MonedaOriginal string        `json:"moneda_original"`
+	// Tasa es cuántas unidades de la moneda pedida vale una de la original
+	Tasa string `json:"tasa"`
+	// Fecha es la de la tabla de tipos de cambio usada
+	Fecha time.Time `json:"fecha"`
+}
+
+// NuevoProducto retorna un producto con los valores por defecto de los
+// campos que el cliente puede omitir
+func NuevoProducto() Producto {
+	precio, _ := dinero.Nuevo(0, MonedaPorDefecto)
+	return Producto{Precio: precio, Activo: true}
+}
+
+// ErrorCampo es un error al decodificar un campo del producto que no se
+// puede expresar como error de tipo, por ejemplo un precio con más
+// decimales de los que admite su moneda
+type ErrorCampo struct {
+	Campo string
+	Err   error
+}
+
+func (e *ErrorCampo) Error() string {
+	return e.Campo + " " + e.Err.Error()
+}
+
+func (e *ErrorCampo) Unwrap() error {
+	return e.Err
+}
+
+// CampoInvalido retorna el campo y el motivo, para la respuesta de error
+func (e *ErrorCampo) CampoInvalido() (string, string) {
+	return e.Campo, e.Err.Error()
 }
 
-// Variable global para almacenar productos en memoria
-var Productos []Producto
-var SiguienteID int = 1
+// MarshalJSON agrega la moneda del precio como campo "moneda"
+func (p Producto) MarshalJSON() ([]byte, error) {
+	type sinMetodos Producto
+	return json.Marshal(struct {
+		sinMetodos
+		Moneda string `json:"moneda"`
+	}{sinMetodos(p), p.Precio.Moneda()})
+}
+
+// UnmarshalJSON parte de NuevoProducto, así un JSON sin moneda o sin
+// activo (incluidos los guardados antes de existir esos campos) toma los
+// valores por defecto en lugar de "" y false. El precio se lee después,
+// con su moneda, como texto decimal.
+func (p *Producto) UnmarshalJSON(datos []byte) error {
+	type sinMetodos Producto
+	entrada := struct {
+		sinMetodos
+		Precio json.RawMessage `json:"precio"`
+		Moneda string          `json:"moneda"`
+		// Conversion es solo de respuesta; se descarta
+		Conversion json.RawMessage `json:"conversion"`
+	}{sinMetodos: sinMetodos(NuevoProducto()), Moneda: MonedaPorDefecto}
+	if err := json.Unmarshal(datos, &entrada); err != nil {
+		return err
+	}
+
+	producto := Producto(entrada.sinMetodos)
+	precio, err := dinero.Nuevo(0, entrada.Moneda)
+	if err != nil {
+		return &ErrorCampo{Campo: "moneda", Err: err}
+	}
+	if len(entrada.Precio) > 0 {
+		if err := precio.UnmarshalJSON(entrada.Precio); err != nil {
+			return &ErrorCampo{Campo: "precio", Err: err}
+		}
+	}
+	producto.Precio = precio
+
+	if err := producto.ValidarPrecios(); err != nil {
+		return err
+	}
+
+	*p = producto
+	return nil
+}
+
+// RegistrarValidaciones hace que las reglas de binding de un importe
+// (required, gt=0 en Precio) se apliquen a sus unidades menores; sin esto
+// el validador las aplica al struct de dinero.Dinero
+func RegistrarValidaciones(v *validator.Validate) {
+	v.RegisterCustomTypeFunc(func(valor reflect.Value) any {
+		return valor.Interface().(dinero.Dinero).Unidades()
+	}, dinero.Dinero{})
+}
+
+// ValidarPrecios comprueba que los precios fijos no repitan la moneda del
+// precio principal
+func (p Producto) ValidarPrecios() error {
+	if _, repetida := p.Precios[p.Precio.Moneda()]; repetida {
+		return &ErrorCampo{Campo: "precios." + p.Precio.Moneda(), Err: errPrecioRepetido}
+	}
+	return nil
+}
+
+var (
+	// errPrecioRepetido es el motivo cuando precios repite la moneda del
+	// precio principal
+	errPrecioRepetido = errors.New("es la moneda del precio principal; va en precio")
+	// errPrecioNoPositivo es el motivo cuando un precio de precios es 0 o
+	// negativo
+	errPrecioNoPositivo = errors.New("debe ser mayor que 0")
+)
+
+// Precios son precios fijos por moneda. En JSON es un objeto de moneda a
+// importe, por ejemplo {"EUR": "829.00"}; la clave define los decimales.
+type Precios map[string]dinero.Dinero
+
+// UnmarshalJSON lee cada importe con la moneda de su clave
+func (p *Precios) UnmarshalJSON(datos []byte) error {
+	var crudos map[string]json.RawMessage
+	if err := json.Unmarshal(datos, &crudos); err != nil {
+		return err
+	}
+	if crudos == nil {
+		*p = nil
+		return nil
+	}
+
+	// En orden, para que con varios errores se informe siempre el mismo
+	monedas := make([]string, 0, len(crudos))
+	for moneda := range crudos {
+		monedas = append(monedas, moneda)
+	}
+	sort.Strings(monedas)
+
+	precios := make(Precios, len(crudos))
+	for _, moneda := range monedas {
+		crudo := crudos[moneda]
+		campo := "precios." + moneda
+		precio, err := dinero.Nuevo(0, moneda)
+		if err != nil {
+			return &ErrorCampo{Campo: campo, Err: err}
+		}
+		if err := precio.UnmarshalJSON(crudo); err != nil {
+			return &ErrorCampo{Campo: campo, Err: err}
+		}
+		if precio.Signo() <= 0 {
+			return &ErrorCampo{Campo: campo, Err: errPrecioNoPositivo}
+		}
+		precios[moneda] = precio
+	}
+	*p = precios
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package openapi sirve el documento OpenAPI 3 de la API y lo compara con
+// las rutas registradas en Gin, para que el documento no quede atrasado.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// documento es la especificación, mantenida a mano en openapi.json
+//
+//go:embed openapi.json
+var documento []byte
+
+// metodos son las operaciones que puede tener un path en OpenAPI
+var metodos = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// operacion es la parte del documento que se usa de cada operación
+type operacion struct {
+	Resumen string `json:"summary"`
+	Cuerpo  *struct {
+		Contenido map[string]struct {
+			Esquema *esquema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// operaciones indexa las operaciones por "MÉTODO /ruta" con la sintaxis
+// de Gin (/productos/:id); se arma una sola vez al iniciar
+var operaciones = func() map[string]operacion {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*esquema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(documento, &doc); err != nil {
+		panic(fmt.Sprintf("openapi: documento inválido: %v", err))
+	}
+
+	ops := make(map[string]operacion)
+	for ruta, item := range doc.Paths {
+		for _, metodo := range metodos {
+			crudo, ok := item[metodo]
+			if !ok {
+				continue
+			}
+			var op operacion
+			if err := json.Unmarshal(crudo, &op); err != nil {
+				panic(fmt.Sprintf("openapi: operación %s %s inválida: %v", metodo, ruta, err))
+			}
+			if op.Cuerpo != nil {
+				for tipo, contenido := range op.Cuerpo.Contenido {
+					if err := contenido.Esquema.resolver(doc.Components.Schemas); err != nil {
+						panic(fmt.Sprintf("openapi: esquema de %s %s (%s): %v", metodo, ruta, tipo, err))
+					}
+				}
+			}
+			ops[strings.ToUpper(metodo)+" "+rutaGin(ruta)] = op
+		}
+	}
+	return ops
+}()
+
+// Handler - GET /openapi.json
+// Sirve el documento OpenAPI
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", documento)
+}
+
+// Endpoints retorna el resumen de cada operación documentada, por
+// "MÉTODO /ruta", para la ruta de bienvenida
+func Endpoints() map[string]string {
+	endpoints := make(map[string]string, len(operaciones))
+	for clave, op := range operaciones {
+		endpoints[clave] = op.Resumen
+	}
+	return endpoints
+}
+
+// Faltantes retorna, ordenadas, las rutas registradas que el documento no
+// describe
+func Faltantes(rutas gin.RoutesInfo) []string {
+	var faltantes []string
+	for _, r := range rutas {
+		clave := r.Method + " " + r.Path
+		if _, ok := operaciones[clave]; !ok {
+			faltantes = append(faltantes, clave)
+		}
+	}
+	sort.Strings(faltantes)
+	return faltantes
+}
+
+// Verificar retorna un error si alguna ruta registrada no está documentada
+func Verificar(rutas gin.RoutesInfo) error {
+	if faltantes := Faltantes(rutas); len(faltantes) > 0 {
+		return fmt.Errorf("rutas sin documentar en openapi.json: %s", strings.Join(faltantes, ", "))
+	}
+	return nil
+}
+
+// rutaGin convierte los parámetros {id} de OpenAPI en :id
+func rutaGin(ruta string) string {
+	partes := strings.Split(ruta, "/")
+	for i, parte := range partes {
+		if strings.HasPrefix(parte, "{") && strings.HasSuffix(parte, "}") {
+			partes[i] = ":" + parte[1:len(parte)-1]
+		}
+	}
+	return strings.Join(partes, "/")
+}
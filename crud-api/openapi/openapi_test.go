@@ -0,0 +1,40 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+
+	"crud-api/auth"
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/metricas"
+	"crud-api/openapi"
+	"crud-api/repository"
+	"crud-api/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRutasDocumentadas falla si alguna ruta registrada no está en
+// openapi.json
+func TestRutasDocumentadas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens, err := auth.NuevoTokens(auth.ConfigTokens{Algoritmo: auth.AlgHS256, Secreto: auth.SecretoAleatorio(), Duracion: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cambios, err := cambio.Abrir("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cambios.Close()
+
+	router := gin.New()
+	routes.SetupRoutes(router, repository.NuevoMemoriaRepository(), auth.NuevoServicio(auth.NuevoUsuarioStore(), tokens, auth.NuevoAPIKeyStore()),
+		routes.Limites{}, metricas.Nuevas(), cambios, busqueda.NuevoIndice())
+
+	if err := openapi.Verificar(router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,429 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"crud-api/errores"
+
+	"github.com/gin-gonic/gin"
+)
+
+// esquema es el subconjunto de JSON Schema (el dialecto de OpenAPI 3.0)
+// que usa el documento para los cuerpos de las peticiones
+type esquema struct {
+	Ref             string              `json:"$ref"`
+	Tipo            string              `json:"type"`
+	Nulable         bool                `json:"nullable"`
+	Requeridos      []string            `json:"required"`
+	Propiedades     map[string]*esquema `json:"properties"`
+	Adicionales     *adicionales        `json:"additionalProperties"`
+	Items           *esquema            `json:"items"`
+	Enum            []any               `json:"enum"`
+	AlgunoDe        []*esquema          `json:"anyOf"`
+	Patron          string              `json:"pattern"`
+	MinLargo        *int                `json:"minLength"`
+	MaxLargo        *int                `json:"maxLength"`
+	MinItems        *int                `json:"minItems"`
+	MaxItems        *int                `json:"maxItems"`
+	Minimo          *float64            `json:"minimum"`
+	Maximo          *float64            `json:"maximum"`
+	MinimoExclusivo bool                `json:"exclusiveMinimum"`
+	MaximoExclusivo bool                `json:"exclusiveMaximum"`
+
+	// destino es el esquema al que apunta $ref, una vez resuelto
+	destino  *esquema
+	resuelto bool
+	// patron es Patron compilado
+	patron *regexp.Regexp
+}
+
+// adicionales es additionalProperties: false, true o un esquema
+type adicionales struct {
+	permitidas bool
+	esquema    *esquema
+}
+
+func (a *adicionales) UnmarshalJSON(datos []byte) error {
+	if err := json.Unmarshal(datos, &a.permitidas); err == nil {
+		return nil
+	}
+	a.permitidas = true
+	return json.Unmarshal(datos, &a.esquema)
+}
+
+// prefijoComponente es el prefijo de las referencias a esquemas
+const prefijoComponente = "#/components/schemas/"
+
+// resolver enlaza las referencias $ref con los esquemas de componentes
+func (e *esquema) resolver(componentes map[string]*esquema) error {
+	if e == nil || e.resuelto {
+		return nil
+	}
+	e.resuelto = true
+
+	if e.Ref != "" {
+		nombre, ok := strings.CutPrefix(e.Ref, prefijoComponente)
+		if !ok || componentes[nombre] == nil {
+			return fmt.Errorf("referencia desconocida %q", e.Ref)
+		}
+		e.destino = componentes[nombre]
+		return e.destino.resolver(componentes)
+	}
+
+	if e.Patron != "" {
+		patron, err := regexp.Compile(e.Patron)
+		if err != nil {
+			return fmt.Errorf("pattern %q inválido: %w", e.Patron, err)
+		}
+		e.patron = patron
+	}
+	for _, alternativa := range e.AlgunoDe {
+		if err := alternativa.resolver(componentes); err != nil {
+			return err
+		}
+	}
+	for _, propiedad := range e.Propiedades {
+		if err := propiedad.resolver(componentes); err != nil {
+			return err
+		}
+	}
+	if e.Adicionales != nil {
+		if err := e.Adicionales.esquema.resolver(componentes); err != nil {
+			return err
+		}
+	}
+	return e.Items.resolver(componentes)
+}
+
+// validar agrega a campos un error por cada regla que valor no cumple.
+// valor es un JSON decodificado con UseNumber y ruta es su ubicación en
+// el cuerpo, por ejemplo "operaciones[2].producto.precio".
+func (e *esquema) validar(valor any, ruta string, campos *[]errores.Campo) {
+	if e.destino != nil {
+		e.destino.validar(valor, ruta, campos)
+		return
+	}
+	invalido := func(formato string, args ...any) {
+		*campos = append(*campos, errores.Campo{Campo: ruta, Mensaje: fmt.Sprintf(formato, args...)})
+	}
+
+	if valor == nil {
+		if !e.Nulable && (e.Tipo != "" || len(e.AlgunoDe) > 0) {
+			invalido("no puede ser null")
+		}
+		return
+	}
+
+	if len(e.AlgunoDe) > 0 {
+		e.validarAlguno(valor, ruta, campos)
+	}
+
+	switch e.Tipo {
+	case "object":
+		objeto, ok := valor.(map[string]any)
+		if !ok {
+			invalido("debe ser un objeto")
+			return
+		}
+		e.validarObjeto(objeto, ruta, campos)
+	case "array":
+		lista, ok := valor.([]any)
+		if !ok {
+			invalido("debe ser una lista")
+			return
+		}
+		if e.MinItems != nil && len(lista) < *e.MinItems {
+			invalido("debe tener al menos %d elementos", *e.MinItems)
+		}
+		if e.MaxItems != nil && len(lista) > *e.MaxItems {
+			invalido("debe tener como máximo %d elementos", *e.MaxItems)
+		}
+		if e.Items != nil {
+			for i, elemento := range lista {
+				e.Items.validar(elemento, fmt.Sprintf("%s[%d]", ruta, i), campos)
+			}
+		}
+	case "string":
+		texto, ok := valor.(string)
+		if !ok {
+			invalido("debe ser un texto")
+			return
+		}
+		largo := utf8.RuneCountInString(texto)
+		if e.MinLargo != nil && largo < *e.MinLargo {
+			if *e.MinLargo == 1 {
+				invalido("no puede estar vacío")
+			} else {
+				invalido("debe tener al menos %d caracteres", *e.MinLargo)
+			}
+		}
+		if e.MaxLargo != nil && largo > *e.MaxLargo {
+			invalido("debe tener como máximo %d caracteres", *e.MaxLargo)
+		}
+		if e.patron != nil && !e.patron.MatchString(texto) {
+			invalido("no tiene el formato esperado (%s)", e.Patron)
+		}
+	case "integer", "number":
+		numero, ok := valor.(json.Number)
+		if !ok {
+			invalido("debe ser un número")
+			return
+		}
+		n, err := numero.Float64()
+		if err != nil {
+			invalido("debe ser un número")
+			return
+		}
+		if e.Tipo == "integer" && n != math.Trunc(n) {
+			invalido("debe ser un número entero")
+			return
+		}
+		e.validarLimites(n, invalido)
+	case "boolean":
+		if _, ok := valor.(bool); !ok {
+			invalido("debe ser true o false")
+			return
+		}
+	}
+
+	if len(e.Enum) > 0 && !e.admite(valor) {
+		opciones := make([]string, len(e.Enum))
+		for i, opcion := range e.Enum {
+			opciones[i] = fmt.Sprint(opcion)
+		}
+		invalido("debe ser uno de: %s", strings.Join(opciones, " "))
+	}
+}
+
+// validarObjeto revisa los campos obligatorios, cada propiedad y los
+// campos que el esquema no declara, en orden alfabético para que los
+// errores salgan siempre igual
+func (e *esquema) validarObjeto(objeto map[string]any, ruta string, campos *[]errores.Campo) {
+	for _, requerido := range e.Requeridos {
+		if _, ok := objeto[requerido]; !ok {
+			*campos = append(*campos, errores.Campo{Campo: unir(ruta, requerido), Mensaje: "es obligatorio"})
+		}
+	}
+
+	claves := make([]string, 0, len(objeto))
+	for clave := range objeto {
+		claves = append(claves, clave)
+	}
+	sort.Strings(claves)
+
+	for _, clave := range claves {
+		if propiedad, ok := e.Propiedades[clave]; ok {
+			propiedad.validar(objeto[clave], unir(ruta, clave), campos)
+			continue
+		}
+		switch {
+		case e.Adicionales == nil:
+			// Sin additionalProperties se admite cualquier campo
+		case !e.Adicionales.permitidas:
+			*campos = append(*campos, errores.Campo{Campo: unir(ruta, clave), Mensaje: "no es un campo permitido"})
+		case e.Adicionales.esquema != nil:
+			e.Adicionales.esquema.validar(objeto[clave], unir(ruta, clave), campos)
+		}
+	}
+}
+
+// validarAlguno aplica anyOf. Las alternativas se distinguen por tipo: si
+// ninguna admite el tipo del valor se informa cuáles se esperaban; si no,
+// el valor debe cumplir alguna de las que lo admiten y, si no cumple
+// ninguna, se informan los errores de la primera.
+func (e *esquema) validarAlguno(valor any, ruta string, campos *[]errores.Campo) {
+	var tipos []string
+	var primeros []errores.Campo
+	candidatas := 0
+	for _, alternativa := range e.AlgunoDe {
+		if alternativa.destino != nil {
+			alternativa = alternativa.destino
+		}
+		tipos = append(tipos, nombreTipo(alternativa.Tipo))
+		if alternativa.Tipo != "" && alternativa.Tipo != tipoDe(valor) &&
+			!(alternativa.Tipo == "number" && tipoDe(valor) == "integer") {
+			continue
+		}
+
+		var errs []errores.Campo
+		alternativa.validar(valor, ruta, &errs)
+		if len(errs) == 0 {
+			return
+		}
+		if candidatas == 0 {
+			primeros = errs
+		}
+		candidatas++
+	}
+
+	if candidatas == 0 {
+		*campos = append(*campos, errores.Campo{Campo: ruta, Mensaje: "debe ser " + strings.Join(tipos, " o ")})
+		return
+	}
+	*campos = append(*campos, primeros...)
+}
+
+// tipoDe retorna el tipo JSON Schema de un valor decodificado con UseNumber
+func tipoDe(valor any) string {
+	switch v := valor.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	}
+	return ""
+}
+
+// nombreTipo describe un tipo JSON Schema para los mensajes
+func nombreTipo(tipo string) string {
+	switch tipo {
+	case "object":
+		return "un objeto"
+	case "array":
+		return "una lista"
+	case "string":
+		return "un texto"
+	case "integer":
+		return "un número entero"
+	case "number":
+		return "un número"
+	case "boolean":
+		return "true o false"
+	}
+	return "cualquier valor"
+}
+
+// validarLimites revisa minimum y maximum
+func (e *esquema) validarLimites(n float64, invalido func(string, ...any)) {
+	if e.Minimo != nil {
+		limite := strconv.FormatFloat(*e.Minimo, 'g', -1, 64)
+		switch {
+		case e.MinimoExclusivo && n <= *e.Minimo:
+			invalido("debe ser mayor que %s", limite)
+		case !e.MinimoExclusivo && n < *e.Minimo:
+			invalido("debe ser mayor o igual que %s", limite)
+		}
+	}
+	if e.Maximo != nil {
+		limite := strconv.FormatFloat(*e.Maximo, 'g', -1, 64)
+		switch {
+		case e.MaximoExclusivo && n >= *e.Maximo:
+			invalido("debe ser menor que %s", limite)
+		case !e.MaximoExclusivo && n > *e.Maximo:
+			invalido("debe ser menor o igual que %s", limite)
+		}
+	}
+}
+
+// admite indica si valor es una de las opciones de enum
+func (e *esquema) admite(valor any) bool {
+	for _, opcion := range e.Enum {
+		if fmt.Sprint(opcion) == fmt.Sprint(valor) {
+			return true
+		}
+	}
+	return false
+}
+
+// unir agrega una propiedad a la ruta de un campo
+func unir(ruta, propiedad string) string {
+	if ruta == "" {
+		return propiedad
+	}
+	return ruta + "." + propiedad
+}
+
+// esquemaCuerpo retorna el esquema que el documento declara para un cuerpo
+// JSON con ese Content-Type. Si la operación acepta un único tipo JSON y el
+// cliente no envió uno conocido se usa ese, como hace ShouldBindJSON.
+// Retorna nil si no hay nada que validar (CSV, NDJSON o un tipo que el
+// handler rechazará).
+func (op operacion) esquemaCuerpo(tipo string) *esquema {
+	if op.Cuerpo == nil {
+		return nil
+	}
+	if contenido, ok := op.Cuerpo.Contenido[tipo]; ok {
+		if !esJSON(tipo) {
+			return nil
+		}
+		return contenido.Esquema
+	}
+	if contenido, ok := op.Cuerpo.Contenido["application/json"]; ok && len(op.Cuerpo.Contenido) == 1 {
+		return contenido.Esquema
+	}
+	return nil
+}
+
+// esJSON indica si un tipo de contenido es JSON
+func esJSON(tipo string) bool {
+	return tipo == "application/json" || strings.HasSuffix(tipo, "+json")
+}
+
+// maxTamanoCuerpo limita los cuerpos JSON que se leen para validarlos;
+// alcanza para un lote de 5000 productos con descripciones largas
+const maxTamanoCuerpo = 16 << 20 // 16 MB
+
+// ValidarCuerpo valida el cuerpo JSON de cada petición contra el esquema
+// que el documento publica para la operación: tipos, campos obligatorios,
+// campos desconocidos, largos y rangos. Si algo no cumple responde 400 con
+// un error por campo; si no, deja el cuerpo intacto para el handler, que
+// sigue aplicando sus propias reglas de binding. Un cuerpo de más de
+// maxTamanoCuerpo se rechaza con 413 sin terminar de leerlo. El
+// Content-Type se compara sin distinguir mayúsculas, como lo leen los
+// handlers.
+func ValidarCuerpo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tipo, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		e := operaciones[c.Request.Method+" "+c.FullPath()].esquemaCuerpo(tipo)
+		if e == nil {
+			c.Next()
+			return
+		}
+
+		cuerpo, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTamanoCuerpo))
+		if err != nil {
+			errores.Responder(c, errores.DesdeBinding(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(cuerpo))
+
+		decoder := json.NewDecoder(bytes.NewReader(cuerpo))
+		decoder.UseNumber()
+		var valor any
+		if err := decoder.Decode(&valor); err != nil {
+			errores.Responder(c, errores.DesdeBinding(err))
+			return
+		}
+
+		var campos []errores.Campo
+		e.validar(valor, "", &campos)
+		if len(campos) > 0 {
+			errValidacion := errores.Nuevo(http.StatusBadRequest, errores.CodigoValidacion, "Los datos enviados no son válidos")
+			errValidacion.Campos = campos
+			errores.Responder(c, errValidacion)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,156 @@
+// Package ratelimit limita las peticiones de cada cliente con un token
+// bucket por cliente (API key verificada o IP). Cada grupo de rutas usa su
+// propio Limitador con su propia configuración.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"crud-api/auth"
+	"crud-api/errores"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config es la configuración de un limitador
+type Config struct {
+	// Tasa es cuántas peticiones por segundo recupera cada cliente; 0
+	// desactiva el límite
+	Tasa float64
+	// Rafaga es cuántas peticiones seguidas acepta como máximo
+	Rafaga int
+	// Inactividad es tras cuánto tiempo sin uso se descarta la cubeta de
+	// un cliente; por defecto el tiempo que tarda en llenarse o un minuto
+	Inactividad time.Duration
+}
+
+// cubeta es el estado de un cliente
+type cubeta struct {
+	tokens float64
+	ultimo time.Time
+}
+
+// Limitador guarda en memoria una cubeta por cliente
+type Limitador struct {
+	cfg Config
+
+	mu       sync.Mutex
+	cubetas  map[string]*cubeta
+	limpieza time.Time // última vez que se descartaron cubetas inactivas
+}
+
+// Nuevo crea un limitador. Con Tasa 0 no limita nada.
+func Nuevo(cfg Config) *Limitador {
+	if cfg.Rafaga < 1 {
+		cfg.Rafaga = 1
+	}
+	if cfg.Inactividad <= 0 && cfg.Tasa > 0 {
+		// Una cubeta inactiva por más de lo que tarda en llenarse es igual
+		// a una nueva, así que se puede descartar sin cambiar nada
+		cfg.Inactividad = max(time.Minute, time.Duration(float64(cfg.Rafaga)/cfg.Tasa*float64(time.Second)))
+	}
+	return &Limitador{cfg: cfg, cubetas: make(map[string]*cubeta), limpieza: time.Now()}
+}
+
+// resultado es lo que decide el limitador para una petición
+type resultado struct {
+	permitida bool
+	restantes int
+	// reintentar es cuánto falta para el próximo token
+	reintentar time.Duration
+	// reinicio es cuánto falta para que la cubeta esté llena
+	reinicio time.Duration
+}
+
+// tomar consume un token del cliente si hay
+func (l *Limitador) tomar(cliente string, ahora time.Time) resultado {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ahora.Sub(l.limpieza) >= l.cfg.Inactividad {
+		l.descartarInactivas(ahora)
+	}
+
+	rafaga := float64(l.cfg.Rafaga)
+	c, ok := l.cubetas[cliente]
+	if !ok {
+		c = &cubeta{tokens: rafaga, ultimo: ahora}
+		l.cubetas[cliente] = c
+	}
+
+	// Recuperar los tokens del tiempo transcurrido
+	c.tokens = math.Min(rafaga, c.tokens+ahora.Sub(c.ultimo).Seconds()*l.cfg.Tasa)
+	c.ultimo = ahora
+
+	r := resultado{}
+	if c.tokens >= 1 {
+		c.tokens--
+		r.permitida = true
+	} else {
+		r.reintentar = l.duracion(1 - c.tokens)
+	}
+	r.restantes = int(c.tokens)
+	r.reinicio = l.duracion(rafaga - c.tokens)
+	return r
+}
+
+// duracion retorna cuánto tarda en recuperar la cantidad de tokens
+func (l *Limitador) duracion(tokens float64) time.Duration {
+	return time.Duration(tokens / l.cfg.Tasa * float64(time.Second))
+}
+
+// descartarInactivas borra las cubetas sin uso reciente. Debe llamarse con
+// el mutex tomado.
+func (l *Limitador) descartarInactivas(ahora time.Time) {
+	for cliente, c := range l.cubetas {
+		if ahora.Sub(c.ultimo) >= l.cfg.Inactividad {
+			delete(l.cubetas, cliente)
+		}
+	}
+	l.limpieza = ahora
+}
+
+// Middleware rechaza con 429 las peticiones de un cliente que superó su
+// límite. Agrega los headers X-RateLimit-* a todas las respuestas.
+func (l *Limitador) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.cfg.Tasa <= 0 {
+			c.Next()
+			return
+		}
+
+		r := l.tomar(Cliente(c), time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(l.cfg.Rafaga))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(r.restantes))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(segundos(r.reinicio)))
+
+		if !r.permitida {
+			espera := segundos(r.reintentar)
+			c.Header("Retry-After", strconv.Itoa(espera))
+			errores.Responder(c, errores.Nuevo(http.StatusTooManyRequests, errores.CodigoDemasiadasPeticiones, "Demasiadas peticiones").
+				ConDetalle("reintente en %d segundos", espera))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Cliente identifica a quien hace la petición: la API key con la que se
+// autenticó o si no su IP. Una clave que no se verificó (auth.Identificar
+// va antes) cuenta como su IP; si no, cambiar de clave falsa en cada
+// petición esquivaría el límite.
+func Cliente(c *gin.Context) string {
+	if identidad, ok := auth.IdentidadDe(c); ok && identidad.EsAPIKey() {
+		return identidad.Sujeto
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// segundos redondea hacia arriba, así el cliente no reintenta antes de tiempo
+func segundos(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,105 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"crud-api/auth"
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/metricas"
+	"crud-api/ratelimit"
+	"crud-api/repository"
+	"crud-api/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nuevoRouter arma el router real con límites bajos para lecturas y login
+func nuevoRouter(t *testing.T) (*gin.Engine, *auth.APIKeyStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tokens, err := auth.NuevoTokens(auth.ConfigTokens{Algoritmo: auth.AlgHS256, Secreto: auth.SecretoAleatorio(), Duracion: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claves := auth.NuevoAPIKeyStore()
+	cambios, err := cambio.Abrir("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cambios.Close() })
+
+	// Tasa casi nula: las cubetas no se recuperan durante el test
+	limite := ratelimit.Config{Tasa: 0.0001, Rafaga: 2}
+	router := gin.New()
+	routes.SetupRoutes(router, repository.NuevoMemoriaRepository(), auth.NuevoServicio(auth.NuevoUsuarioStore(), tokens, claves),
+		routes.Limites{Lectura: limite, Escritura: limite, Auth: limite}, metricas.Nuevas(), cambios, busqueda.NuevoIndice())
+	return router, claves
+}
+
+// pedir hace una petición desde la IP 192.0.2.1, con la API key si no es
+// vacía, y retorna el status
+func pedir(router *gin.Engine, metodo, ruta, cuerpo, clave string) int {
+	peticion := httptest.NewRequest(metodo, ruta, strings.NewReader(cuerpo))
+	peticion.RemoteAddr = "192.0.2.1:1234"
+	peticion.Header.Set("Content-Type", "application/json")
+	if clave != "" {
+		peticion.Header.Set(auth.HeaderAPIKey, clave)
+	}
+	respuesta := httptest.NewRecorder()
+	router.ServeHTTP(respuesta, peticion)
+	return respuesta.Code
+}
+
+func TestClavesFalsasNoEsquivanElLimiteDeLaIP(t *testing.T) {
+	router, claves := nuevoRouter(t)
+	login := `{"usuario":"admin","password":"incorrecta"}`
+
+	// Agotar las cubetas de la IP
+	for i := 0; i < 2; i++ {
+		if status := pedir(router, http.MethodPost, "/auth/token", login, ""); status != http.StatusUnauthorized {
+			t.Fatalf("login %d: status %d, se esperaba 401", i, status)
+		}
+		if status := pedir(router, http.MethodGet, "/productos", "", ""); status != http.StatusOK {
+			t.Fatalf("lectura %d: status %d, se esperaba 200", i, status)
+		}
+	}
+
+	// Una clave distinta en cada petición sigue contando como la IP
+	for i := 0; i < 20; i++ {
+		falsa := fmt.Sprintf("crud_falsa%d", i)
+		if status := pedir(router, http.MethodPost, "/auth/token", login, falsa); status != http.StatusTooManyRequests {
+			t.Fatalf("login con clave falsa %d: status %d, se esperaba 429", i, status)
+		}
+		if status := pedir(router, http.MethodGet, "/productos", "", falsa); status != http.StatusTooManyRequests {
+			t.Fatalf("lectura con clave falsa %d: status %d, se esperaba 429", i, status)
+		}
+	}
+
+	// En las modificaciones las claves falsas se rechazan con 401 y gastan
+	// la cubeta de la IP
+	for i := 0; i < 3; i++ {
+		esperado := http.StatusUnauthorized
+		if i == 2 {
+			esperado = http.StatusTooManyRequests
+		}
+		if status := pedir(router, http.MethodPost, "/productos", "{}", fmt.Sprintf("crud_otra%d", i)); status != esperado {
+			t.Fatalf("escritura con clave falsa %d: status %d, se esperaba %d", i, status, esperado)
+		}
+	}
+
+	// Una clave válida tiene su propia cubeta
+	secreto, _, err := claves.Crear("batch", auth.AlcanceLectura, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := pedir(router, http.MethodGet, "/productos", "", secreto); status != http.StatusOK {
+		t.Fatalf("lectura con clave válida: status %d, se esperaba 200", status)
+	}
+}
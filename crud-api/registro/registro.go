@@ -0,0 +1,100 @@
+// Package registro genera el ID de cada petición y registra las peticiones
+// como logs estructurados con log/slog. El mismo ID viaja en el header
+// X-Request-ID y en las respuestas de error, para cruzar un reporte de un
+// usuario con los logs del servidor.
+package registro
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderID es el header con el que se recibe y se devuelve el ID
+const HeaderID = "X-Request-ID"
+
+// maxLargoID limita los IDs que se aceptan del cliente
+const maxLargoID = 128
+
+// claveID es la clave del ID en el contexto de Gin
+const claveID = "registro.id"
+
+// ID retorna el ID de la petición, o "" si el middleware no corrió
+func ID(c *gin.Context) string {
+	return c.GetString(claveID)
+}
+
+// Logger retorna el logger por defecto con el ID de la petición
+func Logger(c *gin.Context) *slog.Logger {
+	if id := ID(c); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// Middleware asigna el ID de la petición (el de X-Request-ID si es válido,
+// si no uno nuevo) y al terminar registra una línea con el método, la ruta,
+// el status, la latencia y el cliente. Los 5xx se registran como error y
+// los 4xx como warn.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inicio := time.Now()
+
+		id := c.GetHeader(HeaderID)
+		if !idValido(id) {
+			id = nuevoID()
+		}
+		c.Set(claveID, id)
+		c.Header(HeaderID, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		nivel := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			nivel = slog.LevelError
+		case status >= http.StatusBadRequest:
+			nivel = slog.LevelWarn
+		}
+
+		slog.LogAttrs(c.Request.Context(), nivel, "petición",
+			slog.String("request_id", id),
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(inicio).Microseconds())/1000),
+			slog.String("client", c.ClientIP()),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+		)
+	}
+}
+
+// idValido acepta IDs cortos de caracteres seguros para los logs
+func idValido(id string) bool {
+	if id == "" || len(id) > maxLargoID {
+		return false
+	}
+	for _, r := range id {
+		valido := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == '.' || r == ':'
+		if !valido {
+			return false
+		}
+	}
+	return true
+}
+
+// nuevoID genera un ID aleatorio de 32 caracteres hexadecimales
+func nuevoID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,802 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"crud-api/dinero"
+	"crud-api/models"
+)
+
+const (
+	archivoLog      = "productos.log.jsonl"
+	archivoSnapshot = "productos.snapshot.json"
+)
+
+// opLote agrupa en una sola línea del log las entradas de un lote atómico,
+// así un corte a mitad de la escritura no deja el lote aplicado a medias
+const opLote = "batch"
+
+// Operaciones del log que envían un producto a la papelera o lo sacan de
+// ella. Como update, llevan el producto completo; OpEliminar en cambio lo
+// borra definitivamente y solo lo registra Purge.
+const (
+	opPapelera  = "trash"
+	opRestaurar = "restore"
+)
+
+// Operaciones del log sobre categorías; el ID de la entrada es el de la
+// categoría
+const (
+	opCrearCategoria      = "create_category"
+	opActualizarCategoria = "update_category"
+	opEliminarCategoria   = "delete_category"
+)
+
+// entradaLog es una línea del write-ahead log. Op es OpCrear, OpActualizar,
+// OpEliminar, opPapelera, opRestaurar, opLote o una de las operaciones de
+// categorías.
+type entradaLog struct {
+	Op        string            `json:"op"`
+	ID        int               `json:"id,omitempty"`
+	Producto  *models.Producto  `json:"producto,omitempty"`
+	Categoria *models.Categoria `json:"categoria,omitempty"`
+	Lote      []entradaLog      `json:"lote,omitempty"`
+}
+
+// snapshot es el estado completo guardado al compactar el log
+type snapshot struct {
+	SiguienteID          int                `json:"siguiente_id"`
+	Productos            []models.Producto  `json:"productos"`
+	SiguienteIDCategoria int                `json:"siguiente_id_categoria,omitempty"`
+	Categorias           []models.Categoria `json:"categorias,omitempty"`
+}
+
+// ArchivoRepository mantiene los productos y las categorías en memoria y
+// registra cada cambio en un log JSON-lines antes de confirmarlo. Al
+// iniciar reconstruye el estado leyendo el último snapshot y reaplicando
+// el log.
+//
+// Reaplicar una entrada es idempotente (create/update reemplazan por ID,
+// delete ignora IDs inexistentes), así que un corte entre escribir el
+// snapshot y vaciar el log no duplica productos.
+type ArchivoRepository struct {
+	mu          sync.RWMutex
+	dir         string
+	log         *os.File
+	productos   map[int]models.Producto
+	siguienteID int
+
+	categorias           map[int]models.Categoria
+	siguienteIDCategoria int
+
+	detener   chan struct{}
+	hecho     chan struct{}
+	cerrar    sync.Once
+	errCerrar error
+}
+
+// NuevoArchivoRepository abre (o crea) el almacenamiento en el directorio
+// indicado. Si intervalo es mayor que cero, compacta el log periódicamente.
+func NuevoArchivoRepository(dir string, intervalo time.Duration) (*ArchivoRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("crear directorio de datos: %w", err)
+	}
+
+	r := &ArchivoRepository{
+		dir:                  dir,
+		productos:            make(map[int]models.Producto),
+		siguienteID:          1,
+		categorias:           make(map[int]models.Categoria),
+		siguienteIDCategoria: 1,
+		detener:              make(chan struct{}),
+		hecho:                make(chan struct{}),
+	}
+
+	if err := r.cargarSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := r.reproducirLog(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, archivoLog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("abrir log: %w", err)
+	}
+	r.log = f
+
+	if intervalo > 0 {
+		go r.compactarPeriodicamente(intervalo)
+	} else {
+		close(r.hecho)
+	}
+
+	return r, nil
+}
+
+// cargarSnapshot lee el último snapshot, si existe
+func (r *ArchivoRepository) cargarSnapshot() error {
+	datos, err := os.ReadFile(filepath.Join(r.dir, archivoSnapshot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("leer snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := decodificar(datos, &snap); err != nil {
+		return fmt.Errorf("snapshot corrupto: %w", err)
+	}
+
+	for _, producto := range snap.Productos {
+		// Los datos guardados antes de existir Version arrancan en 1
+		producto.Version = max(producto.Version, 1)
+		r.productos[producto.ID] = producto
+	}
+	if snap.SiguienteID > r.siguienteID {
+		r.siguienteID = snap.SiguienteID
+	}
+	for _, categoria := range snap.Categorias {
+		r.categorias[categoria.ID] = categoria
+	}
+	if snap.SiguienteIDCategoria > r.siguienteIDCategoria {
+		r.siguienteIDCategoria = snap.SiguienteIDCategoria
+	}
+	return nil
+}
+
+// reproducirLog aplica las entradas del log sobre el estado del snapshot.
+// Una última línea incompleta (corte durante una escritura) se descarta y
+// se recorta del archivo; una línea inválida en medio del log es un error.
+func (r *ArchivoRepository) reproducirLog() error {
+	ruta := filepath.Join(r.dir, archivoLog)
+	f, err := os.OpenFile(ruta, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("abrir log: %w", err)
+	}
+	defer f.Close()
+
+	lector := bufio.NewReader(f)
+	var offsetValido int64
+	numeroLinea := 0
+
+	for {
+		linea, errLectura := lector.ReadBytes('\n')
+		if len(linea) == 0 && errLectura == io.EOF {
+			break
+		}
+		if errLectura != nil && errLectura != io.EOF {
+			return fmt.Errorf("leer log: %w", errLectura)
+		}
+		numeroLinea++
+
+		// Sin salto de línea final: la escritura se cortó a la mitad
+		if errLectura == io.EOF {
+			slog.Warn("log de productos: se descarta la última línea incompleta", "linea", numeroLinea)
+			break
+		}
+
+		var entrada entradaLog
+		if err := decodificar(bytes.TrimSpace(linea), &entrada); err != nil {
+			// Si es la última línea también se trata como escritura cortada
+			if _, errPeek := lector.Peek(1); errPeek == io.EOF {
+				slog.Warn("log de productos: se descarta una línea inválida", "linea", numeroLinea)
+				break
+			}
+			return fmt.Errorf("log corrupto en la línea %d: %w", numeroLinea, err)
+		}
+
+		r.aplicar(entrada)
+		offsetValido += int64(len(linea))
+	}
+
+	// Recortar lo que se haya descartado para que las nuevas entradas
+	// empiecen en una línea limpia
+	if err := f.Truncate(offsetValido); err != nil {
+		return fmt.Errorf("recortar log: %w", err)
+	}
+	return nil
+}
+
+// decodificar lee el snapshot o una línea del log. Los datos guardados
+// antes de que el precio fuera exacto lo tienen como número JSON, que la
+// API ya no acepta; en ese caso se reintenta con los precios como texto.
+func decodificar(datos []byte, destino any) error {
+	err := json.Unmarshal(datos, destino)
+	if !errors.Is(err, dinero.ErrNoEsTexto) {
+		return err
+	}
+	convertidos, errConvertir := preciosComoTexto(datos)
+	if errConvertir != nil {
+		return err
+	}
+	return json.Unmarshal(convertidos, destino)
+}
+
+// preciosComoTexto reescribe como texto los números JSON de los campos
+// precio y precios, conservando los dígitos tal como se guardaron
+func preciosComoTexto(datos []byte) ([]byte, error) {
+	decodificador := json.NewDecoder(bytes.NewReader(datos))
+	decodificador.UseNumber()
+	var valor any
+	if err := decodificador.Decode(&valor); err != nil {
+		return nil, err
+	}
+
+	var convertir func(valor any)
+	convertir = func(valor any) {
+		switch v := valor.(type) {
+		case map[string]any:
+			for clave, interno := range v {
+				if numero, ok := interno.(json.Number); ok && clave == "precio" {
+					v[clave] = numero.String()
+					continue
+				}
+				if precios, ok := interno.(map[string]any); ok && clave == "precios" {
+					for moneda, precio := range precios {
+						if numero, ok := precio.(json.Number); ok {
+							precios[moneda] = numero.String()
+						}
+					}
+					continue
+				}
+				convertir(interno)
+			}
+		case []any:
+			for _, interno := range v {
+				convertir(interno)
+			}
+		}
+	}
+	convertir(valor)
+	return json.Marshal(valor)
+}
+
+// aplicar modifica el estado en memoria según una entrada del log
+func (r *ArchivoRepository) aplicar(entrada entradaLog) {
+	switch entrada.Op {
+	case opLote:
+		for _, interna := range entrada.Lote {
+			r.aplicar(interna)
+		}
+		return
+	case OpCrear, OpActualizar, opPapelera, opRestaurar:
+		if entrada.Producto != nil {
+			producto := *entrada.Producto
+			producto.Version = max(producto.Version, 1)
+			r.productos[entrada.ID] = producto
+		}
+	case OpEliminar:
+		delete(r.productos, entrada.ID)
+	case opCrearCategoria, opActualizarCategoria, opEliminarCategoria:
+		r.aplicarCategoria(entrada)
+		return
+	}
+
+	// El siguiente ID nunca retrocede, aunque se borre el último producto
+	if entrada.ID >= r.siguienteID {
+		r.siguienteID = entrada.ID + 1
+	}
+}
+
+// aplicarCategoria es aplicar para las entradas de categorías, que tienen
+// su propia secuencia de IDs
+func (r *ArchivoRepository) aplicarCategoria(entrada entradaLog) {
+	if entrada.Op == opEliminarCategoria {
+		delete(r.categorias, entrada.ID)
+	} else if entrada.Categoria != nil {
+		r.categorias[entrada.ID] = *entrada.Categoria
+	}
+
+	if entrada.ID >= r.siguienteIDCategoria {
+		r.siguienteIDCategoria = entrada.ID + 1
+	}
+}
+
+// registrar escribe una entrada en el log y la sincroniza a disco. Si la
+// escritura falla, recorta el log al final de la última entrada buena para
+// que una línea a medias no quede delante de las siguientes.
+// Debe llamarse con el lock de escritura tomado.
+func (r *ArchivoRepository) registrar(entrada entradaLog) error {
+	datos, err := json.Marshal(entrada)
+	if err != nil {
+		return err
+	}
+	datos = append(datos, '\n')
+
+	info, err := r.log.Stat()
+	if err != nil {
+		return fmt.Errorf("escribir log: %w", err)
+	}
+	offsetValido := info.Size()
+
+	if _, err := r.log.Write(datos); err != nil {
+		return r.descartar(offsetValido, fmt.Errorf("escribir log: %w", err))
+	}
+	if err := r.log.Sync(); err != nil {
+		return r.descartar(offsetValido, fmt.Errorf("sincronizar log: %w", err))
+	}
+	return nil
+}
+
+// descartar recorta el log al offset indicado tras una escritura fallida
+// y retorna err junto con el error del recorte, si lo hubo. El log se abre
+// con O_APPEND, así que la siguiente entrada se escribe desde ahí.
+func (r *ArchivoRepository) descartar(offset int64, err error) error {
+	if errRecortar := r.log.Truncate(offset); errRecortar != nil {
+		return errors.Join(err, fmt.Errorf("recortar log: %w", errRecortar))
+	}
+	return err
+}
+
+// Compactar guarda el estado actual en un snapshot y vacía el log
+func (r *ArchivoRepository) Compactar() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snap := snapshot{
+		SiguienteID:          r.siguienteID,
+		Productos:            r.ordenados(),
+		SiguienteIDCategoria: r.siguienteIDCategoria,
+		Categorias:           categoriasOrdenadas(r.categorias),
+	}
+	datos, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Escribir en un temporal y renombrar para que el snapshot sea atómico
+	ruta := filepath.Join(r.dir, archivoSnapshot)
+	tmp := ruta + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("crear snapshot: %w", err)
+	}
+	if _, err := f.Write(datos); err != nil {
+		f.Close()
+		return fmt.Errorf("escribir snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sincronizar snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, ruta); err != nil {
+		return fmt.Errorf("reemplazar snapshot: %w", err)
+	}
+
+	// El snapshot ya contiene todo lo que había en el log
+	if err := r.log.Truncate(0); err != nil {
+		return fmt.Errorf("vaciar log: %w", err)
+	}
+	return r.log.Sync()
+}
+
+// compactarPeriodicamente ejecuta Compactar cada intervalo hasta Close
+func (r *ArchivoRepository) compactarPeriodicamente(intervalo time.Duration) {
+	defer close(r.hecho)
+
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Compactar(); err != nil {
+				slog.Error("error al compactar el log de productos", "error", err)
+			}
+		case <-r.detener:
+			return
+		}
+	}
+}
+
+// Close detiene la compactación, guarda un snapshot final y cierra el log.
+// Las llamadas siguientes no hacen nada y retornan el mismo error.
+func (r *ArchivoRepository) Close() error {
+	r.cerrar.Do(func() {
+		close(r.detener)
+		<-r.hecho
+
+		errCompactar := r.Compactar()
+		r.errCerrar = errors.Join(errCompactar, r.log.Close())
+	})
+	return r.errCerrar
+}
+
+// ordenados retorna los productos ordenados por ID.
+// Debe llamarse con algún lock tomado.
+func (r *ArchivoRepository) ordenados() []models.Producto {
+	productos := make([]models.Producto, 0, len(r.productos))
+	for _, producto := range r.productos {
+		productos = append(productos, producto)
+	}
+	sort.Slice(productos, func(i, j int) bool {
+		return productos[i].ID < productos[j].ID
+	})
+	return productos
+}
+
+// List retorna todos los productos ordenados por ID
+func (r *ArchivoRepository) List() ([]models.Producto, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.ordenados(), nil
+}
+
+// Each recorre una copia de los productos sin mantener el lock mientras
+// se ejecuta fn
+func (r *ArchivoRepository) Each(fn func(models.Producto) error) error {
+	productos, _ := r.List()
+	for _, producto := range productos {
+		if err := fn(producto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count retorna la cantidad de productos fuera de la papelera
+func (r *ArchivoRepository) Count() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cantidad := 0
+	for _, producto := range r.productos {
+		if producto.EliminadoEn == nil {
+			cantidad++
+		}
+	}
+	return cantidad, nil
+}
+
+// Ping verifica que el log siga abierto para registrar operaciones
+func (r *ArchivoRepository) Ping() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, err := r.log.Stat()
+	return err
+}
+
+// Get busca un producto por ID
+func (r *ArchivoRepository) Get(id int) (models.Producto, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	producto, ok := r.productos[id]
+	if !ok {
+		return models.Producto{}, ErrProductoNoEncontrado
+	}
+	return producto, nil
+}
+
+// Create asigna un ID, registra la operación y agrega el producto
+func (r *ArchivoRepository) Create(producto models.Producto) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resultado := r.ejecutar(OperacionLote{Op: OpCrear, Producto: producto})
+	return resultado.Producto, resultado.Err
+}
+
+// Update registra y aplica el reemplazo de un producto existente
+func (r *ArchivoRepository) Update(id int, producto models.Producto) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resultado := r.ejecutar(OperacionLote{Op: OpActualizar, ID: id, Version: producto.Version, Producto: producto})
+	return resultado.Producto, resultado.Err
+}
+
+// Delete registra y aplica el envío de un producto a la papelera
+func (r *ArchivoRepository) Delete(id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ejecutar(OperacionLote{Op: OpEliminar, ID: id, Version: version}).Err
+}
+
+// Restore registra y aplica la salida de un producto de la papelera
+func (r *ArchivoRepository) Restore(id int, version int) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resultado := r.ejecutar(OperacionLote{Op: opRestaurar, ID: id, Version: version})
+	return resultado.Producto, resultado.Err
+}
+
+// Purge registra en una sola línea del log el borrado definitivo de los
+// productos que están en la papelera desde antes de limite
+func (r *ArchivoRepository) Purge(limite time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lote := entradaLog{Op: opLote}
+	for _, producto := range r.ordenados() {
+		if producto.EliminadoEn != nil && producto.EliminadoEn.Before(limite) {
+			lote.Lote = append(lote.Lote, entradaLog{Op: OpEliminar, ID: producto.ID})
+		}
+	}
+	if len(lote.Lote) == 0 {
+		return 0, nil
+	}
+
+	if err := r.registrar(lote); err != nil {
+		return 0, err
+	}
+	r.aplicar(lote)
+	return len(lote.Lote), nil
+}
+
+// Bulk aplica un lote de operaciones. En modo atómico todas las entradas
+// se escriben en una sola línea del log; si algo falla se restaura el
+// estado en memoria anterior al lote.
+func (r *ArchivoRepository) Bulk(operaciones []OperacionLote, atomico bool) ([]ResultadoLote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resultados := make([]ResultadoLote, len(operaciones))
+	if !atomico {
+		for i, operacion := range operaciones {
+			resultados[i] = r.ejecutar(operacion)
+		}
+		return resultados, nil
+	}
+
+	// Respaldo para deshacer el lote si algo falla
+	respaldo, siguienteID := maps.Clone(r.productos), r.siguienteID
+	restaurar := func() {
+		r.productos, r.siguienteID = respaldo, siguienteID
+	}
+
+	lote := entradaLog{Op: opLote}
+	for i, operacion := range operaciones {
+		entrada, producto, err := r.preparar(operacion)
+		if err != nil {
+			restaurar()
+			resultados[i].Err = err
+			return cancelarLote(resultados, i), nil
+		}
+		// Se aplica en memoria enseguida para que las operaciones
+		// siguientes del lote vean su efecto
+		r.aplicar(entrada)
+		lote.Lote = append(lote.Lote, entrada)
+		resultados[i].Producto = producto
+	}
+
+	if err := r.registrar(lote); err != nil {
+		restaurar()
+		return nil, err
+	}
+	return resultados, nil
+}
+
+// ejecutar valida, registra y aplica una sola operación.
+// Debe llamarse con el Lock tomado.
+func (r *ArchivoRepository) ejecutar(operacion OperacionLote) ResultadoLote {
+	entrada, producto, err := r.preparar(operacion)
+	if err != nil {
+		return ResultadoLote{Err: err}
+	}
+	if err := r.registrar(entrada); err != nil {
+		return ResultadoLote{Err: err}
+	}
+
+	r.aplicar(entrada)
+	return ResultadoLote{Producto: producto}
+}
+
+// preparar valida una operación contra el estado actual y arma su entrada
+// de log, sin modificar nada. Debe llamarse con el Lock tomado.
+func (r *ArchivoRepository) preparar(operacion OperacionLote) (entradaLog, models.Producto, error) {
+	producto := operacion.Producto
+
+	switch operacion.Op {
+	case OpCrear:
+		if r.skuEnUso(producto, 0) {
+			return entradaLog{}, models.Producto{}, ErrSKUDuplicado
+		}
+		if r.categoriaDesconocida(producto) {
+			return entradaLog{}, models.Producto{}, ErrCategoriaDesconocida
+		}
+		producto.ID = r.siguienteID
+		producto.Version = 1
+		producto.EliminadoEn = nil
+		producto.CreadoEn = ahora()
+		producto.ActualizadoEn = producto.CreadoEn
+		return entradaLog{Op: OpCrear, ID: producto.ID, Producto: &producto}, producto, nil
+
+	case OpActualizar:
+		actual, ok := r.productos[operacion.ID]
+		if !ok || actual.EliminadoEn != nil {
+			return entradaLog{}, models.Producto{}, ErrProductoNoEncontrado
+		}
+		if operacion.Version != 0 && operacion.Version != actual.Version {
+			return entradaLog{}, models.Producto{}, ErrVersionNoCoincide
+		}
+		if r.skuEnUso(producto, operacion.ID) {
+			return entradaLog{}, models.Producto{}, ErrSKUDuplicado
+		}
+		if r.categoriaDesconocida(producto) {
+			return entradaLog{}, models.Producto{}, ErrCategoriaDesconocida
+		}
+		producto.ID = operacion.ID
+		producto.Version = actual.Version + 1
+		producto.EliminadoEn = nil
+		producto.CreadoEn = actual.CreadoEn
+		producto.ActualizadoEn = ahora()
+		return entradaLog{Op: OpActualizar, ID: producto.ID, Producto: &producto}, producto, nil
+
+	case OpEliminar:
+		actual, ok := r.productos[operacion.ID]
+		if !ok || actual.EliminadoEn != nil {
+			return entradaLog{}, models.Producto{}, ErrProductoNoEncontrado
+		}
+		if operacion.Version != 0 && operacion.Version != actual.Version {
+			return entradaLog{}, models.Producto{}, ErrVersionNoCoincide
+		}
+		eliminado := ahora()
+		actual.EliminadoEn = &eliminado
+		actual.ActualizadoEn = eliminado
+		actual.Version++
+		return entradaLog{Op: opPapelera, ID: actual.ID, Producto: &actual}, models.Producto{}, nil
+
+	case opRestaurar:
+		actual, ok := r.productos[operacion.ID]
+		if !ok {
+			return entradaLog{}, models.Producto{}, ErrProductoNoEncontrado
+		}
+		if actual.EliminadoEn == nil {
+			return entradaLog{}, models.Producto{}, ErrProductoNoEliminado
+		}
+		if operacion.Version != 0 && operacion.Version != actual.Version {
+			return entradaLog{}, models.Producto{}, ErrVersionNoCoincide
+		}
+		actual.EliminadoEn = nil
+		actual.ActualizadoEn = ahora()
+		actual.Version++
+		return entradaLog{Op: opRestaurar, ID: actual.ID, Producto: &actual}, actual, nil
+	}
+
+	return entradaLog{}, models.Producto{}, errOperacionDesconocida(operacion.Op)
+}
+
+// skuEnUso indica si un producto distinto del id ya tiene el SKU.
+// Debe llamarse con algún lock tomado.
+func (r *ArchivoRepository) skuEnUso(producto models.Producto, id int) bool {
+	for _, otro := range r.productos {
+		if skuRepetido(producto, id, otro) {
+			return true
+		}
+	}
+	return false
+}
+
+// categoriaDesconocida indica si el producto está en una categoría que no
+// existe. Debe llamarse con algún lock tomado.
+func (r *ArchivoRepository) categoriaDesconocida(producto models.Producto) bool {
+	_, ok := r.categorias[producto.CategoriaID]
+	return producto.CategoriaID != 0 && !ok
+}
+
+// ListCategorias retorna todas las categorías ordenadas por ID
+func (r *ArchivoRepository) ListCategorias() ([]models.Categoria, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return categoriasOrdenadas(r.categorias), nil
+}
+
+// GetCategoria busca una categoría por ID
+func (r *ArchivoRepository) GetCategoria(id int) (models.Categoria, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categoria, ok := r.categorias[id]
+	if !ok {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	return categoria, nil
+}
+
+// CreateCategoria asigna un ID, registra la operación y agrega la categoría
+func (r *ArchivoRepository) CreateCategoria(categoria models.Categoria) (models.Categoria, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := comprobarCategoria(r.categorias, 0, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.ID = r.siguienteIDCategoria
+	categoria.Ruta = nil
+	categoria.Version = 1
+	categoria.CreadoEn = ahora()
+	categoria.ActualizadoEn = categoria.CreadoEn
+	if err := r.ejecutarCategoria(entradaLog{Op: opCrearCategoria, ID: categoria.ID, Categoria: &categoria}); err != nil {
+		return models.Categoria{}, err
+	}
+	return categoria, nil
+}
+
+// UpdateCategoria registra y aplica el cambio de nombre o de padre
+func (r *ArchivoRepository) UpdateCategoria(id int, categoria models.Categoria) (models.Categoria, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actual, ok := r.categorias[id]
+	if !ok {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	if categoria.Version != 0 && categoria.Version != actual.Version {
+		return models.Categoria{}, ErrVersionNoCoincide
+	}
+	if err := comprobarCategoria(r.categorias, id, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.ID = id
+	categoria.Ruta = nil
+	categoria.Version = actual.Version + 1
+	categoria.CreadoEn = actual.CreadoEn
+	categoria.ActualizadoEn = ahora()
+	if err := r.ejecutarCategoria(entradaLog{Op: opActualizarCategoria, ID: id, Categoria: &categoria}); err != nil {
+		return models.Categoria{}, err
+	}
+	return categoria, nil
+}
+
+// DeleteCategoria registra y aplica la eliminación de una categoría sin
+// subcategorías ni productos
+func (r *ArchivoRepository) DeleteCategoria(id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actual, ok := r.categorias[id]
+	if !ok {
+		return ErrCategoriaNoEncontrada
+	}
+	if version != 0 && version != actual.Version {
+		return ErrVersionNoCoincide
+	}
+	if tieneSubcategorias(r.categorias, id) {
+		return ErrCategoriaEnUso
+	}
+	for _, producto := range r.productos {
+		if producto.CategoriaID == id {
+			return ErrCategoriaEnUso
+		}
+	}
+
+	return r.ejecutarCategoria(entradaLog{Op: opEliminarCategoria, ID: id})
+}
+
+// ejecutarCategoria registra y aplica una entrada de categoría ya
+// validada. Debe llamarse con el Lock tomado.
+func (r *ArchivoRepository) ejecutarCategoria(entrada entradaLog) error {
+	if err := r.registrar(entrada); err != nil {
+		return err
+	}
+	r.aplicar(entrada)
+	return nil
+}
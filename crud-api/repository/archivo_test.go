@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crud-api/dinero"
+	"crud-api/models"
+)
+
+func TestArchivoCloseDosVeces(t *testing.T) {
+	r, err := NuevoArchivoRepository(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("segundo Close: %v", err)
+	}
+}
+
+// TestArchivoEscrituraCortada simula un append que deja media línea en el
+// log: tras descartarla, las entradas siguientes se reaplican al reabrir
+func TestArchivoEscrituraCortada(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NuevoArchivoRepository(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	precio, err := dinero.Nuevo(150, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Create(models.Producto{Nombre: "Primero", SKU: "P-1", Precio: precio}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := r.log.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.log.Write([]byte(`{"op":"create","id":2,"produ`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.descartar(info.Size(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Create(models.Producto{Nombre: "Segundo", SKU: "P-2", Precio: precio}); err != nil {
+		t.Fatal(err)
+	}
+	// Cerrar el log sin compactar para que al reabrir se reaplique
+	if err := r.log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = NuevoArchivoRepository(dir, 0)
+	if err != nil {
+		t.Fatalf("reabrir: %v", err)
+	}
+	defer r.Close()
+	for id, nombre := range map[int]string{1: "Primero", 2: "Segundo"} {
+		producto, err := r.Get(id)
+		if err != nil {
+			t.Fatalf("producto %d: %v", id, err)
+		}
+		if producto.Nombre != nombre {
+			t.Fatalf("producto %d: nombre %q, se esperaba %q", id, producto.Nombre, nombre)
+		}
+	}
+}
+
+// TestArchivoPreciosNumericos carga datos guardados cuando el precio era
+// un número JSON, que la API ya no acepta
+func TestArchivoPreciosNumericos(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := `{"siguiente_id": 2, "productos": [{"id": 1, "nombre": "Viejo", "precio": 899.99}]}`
+	log := `{"op":"update","id":1,"producto":{"id":1,"nombre":"Viejo","precio":19.9,"precios":{"EUR":18}}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, archivoSnapshot), []byte(snapshot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, archivoLog), []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NuevoArchivoRepository(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	producto, err := r.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if producto.Precio.String() != "19.90 USD" || producto.Precios["EUR"].String() != "18.00 EUR" {
+		t.Fatalf("precio %s, precios %v", producto.Precio, producto.Precios)
+	}
+}
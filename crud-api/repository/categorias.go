@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"crud-api/models"
+)
+
+var (
+	// ErrCategoriaNoEncontrada se retorna cuando no existe una categoría con
+	// el ID pedido
+	ErrCategoriaNoEncontrada = errors.New("categoría no encontrada")
+	// ErrCategoriaDesconocida se retorna al guardar un producto cuyo
+	// CategoriaID no es una categoría existente
+	ErrCategoriaDesconocida = errors.New("la categoría del producto no existe")
+	// ErrPadreNoEncontrado se retorna al guardar una categoría cuyo padre
+	// no existe
+	ErrPadreNoEncontrado = errors.New("la categoría padre no existe")
+	// ErrCategoriaDuplicada se retorna cuando otra categoría con el mismo
+	// padre ya tiene ese nombre (sin distinguir mayúsculas)
+	ErrCategoriaDuplicada = errors.New("ya existe una categoría con ese nombre en el mismo nivel")
+	// ErrCicloCategorias se retorna al mover una categoría dentro de sí
+	// misma o de una de sus subcategorías
+	ErrCicloCategorias = errors.New("la categoría no puede quedar dentro de sí misma")
+	// ErrCategoriaEnUso se retorna al eliminar una categoría que tiene
+	// subcategorías o productos
+	ErrCategoriaEnUso = errors.New("la categoría tiene subcategorías o productos")
+)
+
+// CategoriaRepository define el almacenamiento de la taxonomía de
+// categorías. Lo implementa el mismo backend que guarda los productos, así
+// un producto nunca queda en una categoría que no existe.
+type CategoriaRepository interface {
+	// ListCategorias retorna todas las categorías ordenadas por ID
+	ListCategorias() ([]models.Categoria, error)
+	// GetCategoria retorna la categoría con el ID indicado
+	GetCategoria(id int) (models.Categoria, error)
+	// CreateCategoria guarda una categoría nueva con ID, versión 1 y
+	// fechas. Retorna ErrPadreNoEncontrado si el padre no existe y
+	// ErrCategoriaDuplicada si ya hay una con ese nombre en el mismo nivel.
+	CreateCategoria(categoria models.Categoria) (models.Categoria, error)
+	// UpdateCategoria renombra o mueve una categoría; sus subcategorías y
+	// productos la acompañan. Si categoria.Version no es 0 debe coincidir
+	// con la actual. Además de los errores de CreateCategoria retorna
+	// ErrCicloCategorias si el nuevo padre es ella misma o una descendiente.
+	UpdateCategoria(id int, categoria models.Categoria) (models.Categoria, error)
+	// DeleteCategoria elimina una categoría sin subcategorías ni productos,
+	// contando los de la papelera (si no, ErrCategoriaEnUso). Si version no
+	// es 0 debe coincidir.
+	DeleteCategoria(id int, version int) error
+}
+
+// Repositorio es un almacenamiento completo: productos y categorías
+type Repositorio interface {
+	ProductoRepository
+	CategoriaRepository
+}
+
+// comprobarCategoria valida el padre y el nombre de una categoría contra
+// las existentes. id es el de la categoría que se modifica, 0 si es nueva.
+func comprobarCategoria(categorias map[int]models.Categoria, id int, categoria models.Categoria) error {
+	if categoria.PadreID != 0 {
+		if _, ok := categorias[categoria.PadreID]; !ok {
+			return ErrPadreNoEncontrado
+		}
+		// Subiendo desde el nuevo padre hasta la raíz no puede aparecer la
+		// propia categoría; el árbol guardado no tiene ciclos, así que el
+		// recorrido termina
+		for actual := categoria.PadreID; actual != 0; actual = categorias[actual].PadreID {
+			if actual == id {
+				return ErrCicloCategorias
+			}
+		}
+	}
+
+	for _, otra := range categorias {
+		if otra.ID != id && otra.PadreID == categoria.PadreID && strings.EqualFold(otra.Nombre, categoria.Nombre) {
+			return ErrCategoriaDuplicada
+		}
+	}
+	return nil
+}
+
+// tieneSubcategorias indica si alguna categoría tiene como padre a id
+func tieneSubcategorias(categorias map[int]models.Categoria, id int) bool {
+	for _, categoria := range categorias {
+		if categoria.PadreID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// categoriasOrdenadas retorna las categorías del mapa ordenadas por ID
+func categoriasOrdenadas(categorias map[int]models.Categoria) []models.Categoria {
+	lista := make([]models.Categoria, 0, len(categorias))
+	for _, categoria := range categorias {
+		lista = append(lista, categoria)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		return lista[i].ID < lista[j].ID
+	})
+	return lista
+}
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"crud-api/models"
+)
+
+// MemoriaRepository guarda los productos en un slice y las categorías en
+// un mapa, en memoria.
+// Los datos se pierden al reiniciar el servidor.
+//
+// Es seguro para uso concurrente: Gin atiende cada petición en su propia
+// goroutine, así que las lecturas toman un RLock y las escrituras un Lock.
+type MemoriaRepository struct {
+	mu          sync.RWMutex
+	productos   []models.Producto
+	siguienteID int
+
+	categorias           map[int]models.Categoria
+	siguienteIDCategoria int
+}
+
+// NuevoMemoriaRepository crea un repositorio en memoria vacío
+func NuevoMemoriaRepository() *MemoriaRepository {
+	return &MemoriaRepository{
+		productos:            []models.Producto{},
+		siguienteID:          1,
+		categorias:           make(map[int]models.Categoria),
+		siguienteIDCategoria: 1,
+	}
+}
+
+// List retorna una copia de todos los productos
+func (r *MemoriaRepository) List() ([]models.Producto, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Copiar para que el llamador no comparta el slice interno
+	productos := make([]models.Producto, len(r.productos))
+	copy(productos, r.productos)
+	return productos, nil
+}
+
+// Each recorre una copia de los productos, así fn puede tardar (por
+// ejemplo escribiendo a la red) sin bloquear a las demás peticiones
+func (r *MemoriaRepository) Each(fn func(models.Producto) error) error {
+	productos, _ := r.List()
+	for _, producto := range productos {
+		if err := fn(producto); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count retorna la cantidad de productos fuera de la papelera
+func (r *MemoriaRepository) Count() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cantidad := 0
+	for _, producto := range r.productos {
+		if producto.EliminadoEn == nil {
+			cantidad++
+		}
+	}
+	return cantidad, nil
+}
+
+// Ping siempre tiene éxito: la memoria no puede fallar
+func (r *MemoriaRepository) Ping() error {
+	return nil
+}
+
+// Get busca un producto por ID
+func (r *MemoriaRepository) Get(id int) (models.Producto, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, producto := range r.productos {
+		if producto.ID == id {
+			return producto, nil
+		}
+	}
+	return models.Producto{}, ErrProductoNoEncontrado
+}
+
+// Create asigna un ID automático y agrega el producto a la lista
+func (r *MemoriaRepository) Create(producto models.Producto) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.crear(producto)
+}
+
+// Update reemplaza un producto manteniendo su ID original
+func (r *MemoriaRepository) Update(id int, producto models.Producto) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.actualizar(id, producto)
+}
+
+// Delete envía un producto a la papelera
+func (r *MemoriaRepository) Delete(id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.eliminar(id, version)
+}
+
+// Restore saca un producto de la papelera
+func (r *MemoriaRepository) Restore(id int, version int) (models.Producto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, producto := range r.productos {
+		if producto.ID == id {
+			if producto.EliminadoEn == nil {
+				return models.Producto{}, ErrProductoNoEliminado
+			}
+			if version != 0 && version != producto.Version {
+				return models.Producto{}, ErrVersionNoCoincide
+			}
+			producto.EliminadoEn = nil
+			producto.Version++
+			producto.ActualizadoEn = ahora()
+			r.productos[i] = producto
+			return producto, nil
+		}
+	}
+	return models.Producto{}, ErrProductoNoEncontrado
+}
+
+// Purge quita del slice los productos que están en la papelera desde
+// antes de limite
+func (r *MemoriaRepository) Purge(limite time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	antes := len(r.productos)
+	r.productos = slices.DeleteFunc(r.productos, func(producto models.Producto) bool {
+		return producto.EliminadoEn != nil && producto.EliminadoEn.Before(limite)
+	})
+	return antes - len(r.productos), nil
+}
+
+// Bulk aplica un lote de operaciones con el lock tomado todo el tiempo,
+// así ninguna otra petición ve un lote a medio aplicar
+func (r *MemoriaRepository) Bulk(operaciones []OperacionLote, atomico bool) ([]ResultadoLote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Respaldo para deshacer el lote si es atómico y algo falla
+	respaldo, siguienteID := slices.Clone(r.productos), r.siguienteID
+
+	resultados := make([]ResultadoLote, len(operaciones))
+	for i, operacion := range operaciones {
+		resultados[i] = r.aplicar(operacion)
+		if resultados[i].Err != nil && atomico {
+			r.productos, r.siguienteID = respaldo, siguienteID
+			return cancelarLote(resultados, i), nil
+		}
+	}
+	return resultados, nil
+}
+
+// aplicar ejecuta una operación de lote. Debe llamarse con el Lock tomado.
+func (r *MemoriaRepository) aplicar(operacion OperacionLote) ResultadoLote {
+	switch operacion.Op {
+	case OpCrear:
+		producto, err := r.crear(operacion.Producto)
+		return ResultadoLote{Producto: producto, Err: err}
+	case OpActualizar:
+		operacion.Producto.Version = operacion.Version
+		producto, err := r.actualizar(operacion.ID, operacion.Producto)
+		return ResultadoLote{Producto: producto, Err: err}
+	case OpEliminar:
+		return ResultadoLote{Err: r.eliminar(operacion.ID, operacion.Version)}
+	}
+	return ResultadoLote{Err: errOperacionDesconocida(operacion.Op)}
+}
+
+// crear debe llamarse con el Lock tomado
+func (r *MemoriaRepository) crear(producto models.Producto) (models.Producto, error) {
+	if r.skuEnUso(producto, 0) {
+		return models.Producto{}, ErrSKUDuplicado
+	}
+	if r.categoriaDesconocida(producto) {
+		return models.Producto{}, ErrCategoriaDesconocida
+	}
+
+	producto.ID = r.siguienteID
+	producto.Version = 1
+	producto.EliminadoEn = nil
+	producto.CreadoEn = ahora()
+	producto.ActualizadoEn = producto.CreadoEn
+	r.siguienteID++
+
+	r.productos = append(r.productos, producto)
+	return producto, nil
+}
+
+// actualizar debe llamarse con el Lock tomado
+func (r *MemoriaRepository) actualizar(id int, producto models.Producto) (models.Producto, error) {
+	for i := range r.productos {
+		if r.productos[i].ID == id && r.productos[i].EliminadoEn == nil {
+			if producto.Version != 0 && producto.Version != r.productos[i].Version {
+				return models.Producto{}, ErrVersionNoCoincide
+			}
+			if r.skuEnUso(producto, id) {
+				return models.Producto{}, ErrSKUDuplicado
+			}
+			if r.categoriaDesconocida(producto) {
+				return models.Producto{}, ErrCategoriaDesconocida
+			}
+			producto.ID = id
+			producto.Version = r.productos[i].Version + 1
+			producto.EliminadoEn = nil
+			producto.CreadoEn = r.productos[i].CreadoEn
+			producto.ActualizadoEn = ahora()
+			r.productos[i] = producto
+			return producto, nil
+		}
+	}
+	return models.Producto{}, ErrProductoNoEncontrado
+}
+
+// skuEnUso indica si un producto distinto del id ya tiene el SKU.
+// Debe llamarse con algún lock tomado.
+func (r *MemoriaRepository) skuEnUso(producto models.Producto, id int) bool {
+	for _, otro := range r.productos {
+		if skuRepetido(producto, id, otro) {
+			return true
+		}
+	}
+	return false
+}
+
+// categoriaDesconocida indica si el producto está en una categoría que no
+// existe. Debe llamarse con algún lock tomado.
+func (r *MemoriaRepository) categoriaDesconocida(producto models.Producto) bool {
+	_, ok := r.categorias[producto.CategoriaID]
+	return producto.CategoriaID != 0 && !ok
+}
+
+// eliminar envía un producto a la papelera. Debe llamarse con el Lock
+// tomado.
+func (r *MemoriaRepository) eliminar(id int, version int) error {
+	for i, producto := range r.productos {
+		if producto.ID == id && producto.EliminadoEn == nil {
+			if version != 0 && version != producto.Version {
+				return ErrVersionNoCoincide
+			}
+			eliminado := ahora()
+			producto.EliminadoEn = &eliminado
+			producto.ActualizadoEn = eliminado
+			producto.Version++
+			r.productos[i] = producto
+			return nil
+		}
+	}
+	return ErrProductoNoEncontrado
+}
+
+// ListCategorias retorna todas las categorías ordenadas por ID
+func (r *MemoriaRepository) ListCategorias() ([]models.Categoria, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return categoriasOrdenadas(r.categorias), nil
+}
+
+// GetCategoria busca una categoría por ID
+func (r *MemoriaRepository) GetCategoria(id int) (models.Categoria, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categoria, ok := r.categorias[id]
+	if !ok {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	return categoria, nil
+}
+
+// CreateCategoria asigna un ID y agrega la categoría
+func (r *MemoriaRepository) CreateCategoria(categoria models.Categoria) (models.Categoria, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := comprobarCategoria(r.categorias, 0, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.ID = r.siguienteIDCategoria
+	categoria.Ruta = nil
+	categoria.Version = 1
+	categoria.CreadoEn = ahora()
+	categoria.ActualizadoEn = categoria.CreadoEn
+	r.siguienteIDCategoria++
+
+	r.categorias[categoria.ID] = categoria
+	return categoria, nil
+}
+
+// UpdateCategoria renombra o mueve una categoría
+func (r *MemoriaRepository) UpdateCategoria(id int, categoria models.Categoria) (models.Categoria, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actual, ok := r.categorias[id]
+	if !ok {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	if categoria.Version != 0 && categoria.Version != actual.Version {
+		return models.Categoria{}, ErrVersionNoCoincide
+	}
+	if err := comprobarCategoria(r.categorias, id, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.ID = id
+	categoria.Ruta = nil
+	categoria.Version = actual.Version + 1
+	categoria.CreadoEn = actual.CreadoEn
+	categoria.ActualizadoEn = ahora()
+	r.categorias[id] = categoria
+	return categoria, nil
+}
+
+// DeleteCategoria elimina una categoría sin subcategorías ni productos
+func (r *MemoriaRepository) DeleteCategoria(id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	actual, ok := r.categorias[id]
+	if !ok {
+		return ErrCategoriaNoEncontrada
+	}
+	if version != 0 && version != actual.Version {
+		return ErrVersionNoCoincide
+	}
+	if tieneSubcategorias(r.categorias, id) {
+		return ErrCategoriaEnUso
+	}
+	for _, producto := range r.productos {
+		if producto.CategoriaID == id {
+			return ErrCategoriaEnUso
+		}
+	}
+
+	delete(r.categorias, id)
+	return nil
+}
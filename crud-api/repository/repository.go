@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"crud-api/models"
+)
+
+var (
+	// ErrProductoNoEncontrado se retorna cuando no existe un producto con el ID pedido
+	ErrProductoNoEncontrado = errors.New("producto no encontrado")
+	// ErrVersionNoCoincide se retorna cuando el producto cambió desde que
+	// el cliente lo leyó (la versión esperada no es la actual)
+	ErrVersionNoCoincide = errors.New("la versión del producto no coincide")
+	// ErrLoteCancelado marca las operaciones de un lote atómico que no se
+	// aplicaron porque otra operación del mismo lote falló
+	ErrLoteCancelado = errors.New("el lote se canceló porque otra operación falló")
+	// ErrSKUDuplicado se retorna al crear o actualizar un producto con el
+	// SKU de otro producto
+	ErrSKUDuplicado = errors.New("ya existe un producto con ese SKU")
+	// ErrProductoNoEliminado se retorna al restaurar un producto que no
+	// está en la papelera
+	ErrProductoNoEliminado = errors.New("el producto no está en la papelera")
+)
+
+// Tipos de operación de un lote
+const (
+	OpCrear      = "create"
+	OpActualizar = "update"
+	OpEliminar   = "delete"
+)
+
+// OperacionLote es una operación dentro de un Bulk
+type OperacionLote struct {
+	Op       string          // OpCrear, OpActualizar u OpEliminar
+	ID       int             // producto a actualizar o eliminar
+	Version  int             // versión esperada (0 = sin condición)
+	Producto models.Producto // datos para crear o actualizar
+}
+
+// ResultadoLote es el resultado de una operación de un Bulk
+type ResultadoLote struct {
+	Producto models.Producto // producto creado o actualizado
+	Err      error
+}
+
+// ProductoRepository define las operaciones de almacenamiento de productos.
+// Los handlers dependen solo de esta interfaz, así se puede cambiar el
+// backend (memoria, base de datos, archivo) sin tocar la lógica HTTP.
+//
+// Eliminar un producto lo envía a la papelera (EliminadoEn deja de ser
+// nil): List, Each y Get lo siguen retornando, para que los handlers
+// decidan si mostrarlo, pero Update y Delete lo tratan como inexistente.
+// Mientras está en la papelera conserva su SKU y su categoría.
+type ProductoRepository interface {
+	// List retorna todos los productos, también los de la papelera
+	List() ([]models.Producto, error)
+	// Each llama a fn con cada producto en orden de ID, sin armar la lista
+	// completa. Si fn retorna un error el recorrido se corta y se retorna.
+	Each(fn func(models.Producto) error) error
+	// Get retorna el producto con el ID indicado, aunque esté en la papelera
+	Get(id int) (models.Producto, error)
+	// Create guarda un producto nuevo asignándole un ID, la versión 1 y
+	// las fechas de creación y actualización. Si el SKU ya lo usa otro
+	// producto retorna ErrSKUDuplicado.
+	Create(producto models.Producto) (models.Producto, error)
+	// Update reemplaza el producto con el ID indicado, incrementa su
+	// versión y actualiza ActualizadoEn (CreadoEn se conserva). Si
+	// producto.Version no es 0 debe coincidir con la actual. Igual que
+	// Create, retorna ErrSKUDuplicado si el SKU es de otro producto.
+	Update(id int, producto models.Producto) (models.Producto, error)
+	// Delete envía a la papelera el producto con el ID indicado, asignando
+	// EliminadoEn e incrementando su versión. Si version no es 0 debe
+	// coincidir con la actual.
+	Delete(id int, version int) error
+	// Restore saca un producto de la papelera e incrementa su versión.
+	// Retorna ErrProductoNoEliminado si no estaba en la papelera; si
+	// version no es 0 debe coincidir con la actual.
+	Restore(id int, version int) (models.Producto, error)
+	// Purge borra definitivamente los productos que están en la papelera
+	// desde antes de limite y retorna cuántos borró
+	Purge(limite time.Time) (int, error)
+	// Bulk aplica varias operaciones en orden y retorna un resultado por
+	// cada una. Si atomico es true se aplican todas o ninguna: al fallar
+	// una, el resto queda con ErrLoteCancelado.
+	Bulk(operaciones []OperacionLote, atomico bool) ([]ResultadoLote, error)
+	// Count retorna la cantidad de productos, sin contar la papelera
+	Count() (int, error)
+	// Ping verifica que el almacenamiento pueda atender operaciones
+	Ping() error
+}
+
+// ahora retorna el instante con el que se marcan las modificaciones
+func ahora() time.Time {
+	return time.Now().UTC()
+}
+
+// skuRepetido indica si otro producto (con ID distinto de id) ya usa el
+// SKU de producto
+func skuRepetido(producto models.Producto, id int, otro models.Producto) bool {
+	return producto.SKU != "" && otro.ID != id && otro.SKU == producto.SKU
+}
+
+// cancelarLote arma los resultados de un lote atómico que falló en la
+// operación indicada: esa conserva su error y las demás se marcan canceladas
+func cancelarLote(resultados []ResultadoLote, fallida int) []ResultadoLote {
+	for i := range resultados {
+		if i != fallida {
+			resultados[i] = ResultadoLote{Err: ErrLoteCancelado}
+		}
+	}
+	return resultados
+}
+
+// errOperacionDesconocida se usa cuando un lote trae un tipo de operación inválido
+func errOperacionDesconocida(op string) error {
+	return fmt.Errorf("operación desconocida %q", op)
+}
@@ -0,0 +1,710 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"crud-api/dinero"
+	"crud-api/models"
+
+	// Driver de SQLite escrito en Go puro (no necesita cgo)
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// migraciones contiene el esquema de la base de datos en orden.
+// Cada entrada se aplica una sola vez; para cambiar el esquema se agrega
+// una migración nueva al final, nunca se edita una existente.
+var migraciones = []string{
+	// 1: tabla de productos
+	`CREATE TABLE productos (
+		id     INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT    NOT NULL,
+		precio REAL    NOT NULL
+	)`,
+	// 2: versión para control de concurrencia optimista
+	`ALTER TABLE productos ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	// 3-12: SKU único (si se indica), descripción, categoría, moneda,
+	// stock, activo y fechas; los productos existentes quedan fechados
+	// en el momento de la migración
+	`ALTER TABLE productos ADD COLUMN sku TEXT NOT NULL DEFAULT ''`,
+	`CREATE UNIQUE INDEX productos_sku ON productos (sku) WHERE sku <> ''`,
+	`ALTER TABLE productos ADD COLUMN descripcion TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE productos ADD COLUMN categoria TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE productos ADD COLUMN moneda TEXT NOT NULL DEFAULT 'USD'`,
+	`ALTER TABLE productos ADD COLUMN stock INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE productos ADD COLUMN activo INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE productos ADD COLUMN creado_en TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE productos ADD COLUMN actualizado_en TEXT NOT NULL DEFAULT ''`,
+	`UPDATE productos SET
+		creado_en = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'),
+		actualizado_en = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+	 WHERE creado_en = ''`,
+	// 13-15: el precio pasa de REAL a un entero de unidades menores de su
+	// moneda (centavos en USD), para guardarlo sin errores de redondeo
+	`ALTER TABLE productos ADD COLUMN precio_unidades INTEGER NOT NULL DEFAULT 0`,
+	`UPDATE productos SET precio_unidades = CAST(ROUND(precio * CASE
+		WHEN moneda IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+			'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+		WHEN moneda IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+		WHEN moneda IN ('CLF', 'UYW') THEN 10000
+		ELSE 100 END) AS INTEGER)`,
+	`ALTER TABLE productos DROP COLUMN precio`,
+	// 16: precios fijos en otras monedas, como objeto JSON moneda → importe
+	`ALTER TABLE productos ADD COLUMN precios TEXT NOT NULL DEFAULT '{}'`,
+	// 17-20: taxonomía de categorías (padre_id 0 es una raíz) y la
+	// categoría de cada producto (0 si no tiene)
+	`CREATE TABLE categorias (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre         TEXT    NOT NULL,
+		padre_id       INTEGER NOT NULL DEFAULT 0,
+		creado_en      TEXT    NOT NULL,
+		actualizado_en TEXT    NOT NULL,
+		version        INTEGER NOT NULL DEFAULT 1
+	)`,
+	`CREATE INDEX categorias_padre ON categorias (padre_id)`,
+	`ALTER TABLE productos ADD COLUMN categoria_id INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX productos_categoria ON productos (categoria_id)`,
+	// 21-22: papelera; eliminado_en es NULL si el producto no está en ella
+	`ALTER TABLE productos ADD COLUMN eliminado_en TEXT`,
+	`CREATE INDEX productos_eliminado ON productos (eliminado_en) WHERE eliminado_en IS NOT NULL`,
+}
+
+// columnasProducto son las columnas que lee escanearProducto, en orden
+const columnasProducto = `id, sku, nombre, descripcion, categoria, categoria_id, precio_unidades, moneda, precios, stock, activo, creado_en, actualizado_en, version, eliminado_en`
+
+// fila es lo que tienen en común *sql.Row y *sql.Rows
+type fila interface {
+	Scan(dest ...any) error
+}
+
+// escanearProducto lee un producto seleccionado con columnasProducto.
+// El precio se guarda en unidades menores, los precios fijos como JSON y
+// las fechas como texto RFC 3339 en UTC.
+func escanearProducto(f fila) (models.Producto, error) {
+	var producto models.Producto
+	var unidades int64
+	var moneda, precios, creado, actualizado string
+	var eliminado sql.NullString
+	err := f.Scan(&producto.ID, &producto.SKU, &producto.Nombre, &producto.Descripcion, &producto.Categoria,
+		&producto.CategoriaID, &unidades, &moneda, &precios, &producto.Stock, &producto.Activo, &creado, &actualizado, &producto.Version,
+		&eliminado)
+	if err != nil {
+		return models.Producto{}, err
+	}
+	if producto.Precio, err = dinero.Nuevo(unidades, moneda); err != nil {
+		return models.Producto{}, fmt.Errorf("producto %d: moneda %q: %w", producto.ID, moneda, err)
+	}
+	if err = json.Unmarshal([]byte(precios), &producto.Precios); err != nil {
+		return models.Producto{}, fmt.Errorf("producto %d: precios inválidos: %w", producto.ID, err)
+	}
+	if len(producto.Precios) == 0 {
+		producto.Precios = nil
+	}
+	if producto.CreadoEn, err = time.Parse(time.RFC3339Nano, creado); err != nil {
+		return models.Producto{}, fmt.Errorf("producto %d: fecha de creación inválida: %w", producto.ID, err)
+	}
+	if producto.ActualizadoEn, err = time.Parse(time.RFC3339Nano, actualizado); err != nil {
+		return models.Producto{}, fmt.Errorf("producto %d: fecha de actualización inválida: %w", producto.ID, err)
+	}
+	if eliminado.Valid {
+		fechaEliminado, err := time.Parse(time.RFC3339Nano, eliminado.String)
+		if err != nil {
+			return models.Producto{}, fmt.Errorf("producto %d: fecha de eliminación inválida: %w", producto.ID, err)
+		}
+		producto.EliminadoEn = &fechaEliminado
+	}
+	return producto, nil
+}
+
+// preciosJSON convierte los precios fijos al texto que se guarda en la base
+func preciosJSON(precios models.Precios) (string, error) {
+	if len(precios) == 0 {
+		return "{}", nil
+	}
+	datos, err := json.Marshal(precios)
+	return string(datos), err
+}
+
+// fecha convierte un instante al texto que se guarda en la base
+func fecha(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// traducirError convierte la violación del índice único de SKU en
+// ErrSKUDuplicado
+func traducirError(err error) error {
+	var errSQLite *sqlite.Error
+	if errors.As(err, &errSQLite) && errSQLite.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrSKUDuplicado
+	}
+	return err
+}
+
+// SQLiteRepository guarda los productos y las categorías en un archivo SQLite
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NuevoSQLiteRepository abre (o crea) la base de datos en la ruta indicada
+// y aplica las migraciones pendientes
+func NuevoSQLiteRepository(ruta string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", ruta)
+	if err != nil {
+		return nil, fmt.Errorf("abrir base de datos: %w", err)
+	}
+
+	// SQLite admite un solo escritor a la vez; una única conexión evita
+	// errores "database is locked" con peticiones concurrentes
+	db.SetMaxOpenConns(1)
+
+	if err := migrar(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// migrar aplica las migraciones que todavía no se ejecutaron
+func migrar(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("crear tabla de migraciones: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("leer versión del esquema: %w", err)
+	}
+
+	for i := version; i < len(migraciones); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migraciones[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migración %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migración %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migración %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Close cierra la conexión con la base de datos
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// List retorna todos los productos ordenados por ID
+func (r *SQLiteRepository) List() ([]models.Producto, error) {
+	rows, err := r.db.Query(`SELECT ` + columnasProducto + ` FROM productos ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	productos := []models.Producto{}
+	for rows.Next() {
+		producto, err := escanearProducto(rows)
+		if err != nil {
+			return nil, err
+		}
+		productos = append(productos, producto)
+	}
+	return productos, rows.Err()
+}
+
+// Count retorna la cantidad de productos fuera de la papelera
+func (r *SQLiteRepository) Count() (int, error) {
+	var cantidad int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM productos WHERE eliminado_en IS NULL`).Scan(&cantidad)
+	return cantidad, err
+}
+
+// Ping verifica que la base de datos responda
+func (r *SQLiteRepository) Ping() error {
+	return r.db.Ping()
+}
+
+// tamanoPaginaEach es la cantidad de filas que Each lee por consulta
+const tamanoPaginaEach = 500
+
+// Each recorre los productos de a páginas por ID. No mantiene un cursor
+// abierto mientras corre fn: con una sola conexión, un cliente lento
+// bloquearía todas las demás consultas.
+func (r *SQLiteRepository) Each(fn func(models.Producto) error) error {
+	ultimoID := 0
+	for {
+		pagina, err := r.paginaDesde(ultimoID)
+		if err != nil {
+			return err
+		}
+		for _, producto := range pagina {
+			if err := fn(producto); err != nil {
+				return err
+			}
+		}
+		if len(pagina) < tamanoPaginaEach {
+			return nil
+		}
+		ultimoID = pagina[len(pagina)-1].ID
+	}
+}
+
+// paginaDesde lee hasta tamanoPaginaEach productos con ID mayor que desde
+func (r *SQLiteRepository) paginaDesde(desde int) ([]models.Producto, error) {
+	rows, err := r.db.Query(`SELECT `+columnasProducto+` FROM productos WHERE id > ? ORDER BY id LIMIT ?`, desde, tamanoPaginaEach)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	productos := make([]models.Producto, 0, tamanoPaginaEach)
+	for rows.Next() {
+		producto, err := escanearProducto(rows)
+		if err != nil {
+			return nil, err
+		}
+		productos = append(productos, producto)
+	}
+	return productos, rows.Err()
+}
+
+// Get busca un producto por ID
+func (r *SQLiteRepository) Get(id int) (models.Producto, error) {
+	producto, err := escanearProducto(r.db.QueryRow(`SELECT `+columnasProducto+` FROM productos WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Producto{}, ErrProductoNoEncontrado
+	}
+	return producto, err
+}
+
+// ejecutor es lo que tienen en común *sql.DB y *sql.Tx, para que las
+// operaciones sirvan tanto sueltas como dentro de un lote
+type ejecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Create inserta un producto; SQLite asigna el ID
+func (r *SQLiteRepository) Create(producto models.Producto) (models.Producto, error) {
+	return crearSQLite(r.db, producto)
+}
+
+// Update reemplaza un producto manteniendo su ID original
+func (r *SQLiteRepository) Update(id int, producto models.Producto) (models.Producto, error) {
+	return actualizarSQLite(r.db, id, producto)
+}
+
+// Delete envía un producto a la papelera
+func (r *SQLiteRepository) Delete(id int, version int) error {
+	return eliminarSQLite(r.db, id, version)
+}
+
+// Restore saca un producto de la papelera
+func (r *SQLiteRepository) Restore(id int, version int) (models.Producto, error) {
+	producto, err := escanearProducto(r.db.QueryRow(
+		`UPDATE productos SET eliminado_en = NULL, actualizado_en = ?, version = version + 1
+		 WHERE id = ? AND eliminado_en IS NOT NULL AND (? = 0 OR version = ?)
+		 RETURNING `+columnasProducto,
+		fecha(ahora()), id, version, version,
+	))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return producto, err
+	}
+
+	var versionActual int
+	var eliminado bool
+	err = r.db.QueryRow(`SELECT version, eliminado_en IS NOT NULL FROM productos WHERE id = ?`, id).Scan(&versionActual, &eliminado)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return models.Producto{}, ErrProductoNoEncontrado
+	case err != nil:
+		return models.Producto{}, err
+	case !eliminado:
+		return models.Producto{}, ErrProductoNoEliminado
+	}
+	return models.Producto{}, ErrVersionNoCoincide
+}
+
+// Purge borra los productos que están en la papelera desde antes de
+// limite. julianday compara los instantes aunque el texto RFC 3339 tenga
+// distinta cantidad de decimales.
+func (r *SQLiteRepository) Purge(limite time.Time) (int, error) {
+	res, err := r.db.Exec(
+		`DELETE FROM productos WHERE eliminado_en IS NOT NULL AND julianday(eliminado_en) < julianday(?)`,
+		fecha(limite),
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Bulk aplica un lote dentro de una transacción. En modo por operación
+// cada una usa un SAVEPOINT, así las que fallan se deshacen sin afectar
+// al resto y todo se confirma con un solo commit.
+func (r *SQLiteRepository) Bulk(operaciones []OperacionLote, atomico bool) ([]ResultadoLote, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resultados := make([]ResultadoLote, len(operaciones))
+	for i, operacion := range operaciones {
+		if !atomico {
+			if _, err := tx.Exec(`SAVEPOINT operacion`); err != nil {
+				return nil, err
+			}
+		}
+
+		resultados[i] = aplicarSQLite(tx, operacion)
+
+		if atomico {
+			if resultados[i].Err != nil {
+				return cancelarLote(resultados, i), nil
+			}
+			continue
+		}
+
+		if resultados[i].Err != nil {
+			if _, err := tx.Exec(`ROLLBACK TO operacion`); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.Exec(`RELEASE operacion`); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return resultados, nil
+}
+
+// aplicarSQLite ejecuta una operación de lote
+func aplicarSQLite(e ejecutor, operacion OperacionLote) ResultadoLote {
+	switch operacion.Op {
+	case OpCrear:
+		producto, err := crearSQLite(e, operacion.Producto)
+		return ResultadoLote{Producto: producto, Err: err}
+	case OpActualizar:
+		operacion.Producto.Version = operacion.Version
+		producto, err := actualizarSQLite(e, operacion.ID, operacion.Producto)
+		return ResultadoLote{Producto: producto, Err: err}
+	case OpEliminar:
+		return ResultadoLote{Err: eliminarSQLite(e, operacion.ID, operacion.Version)}
+	}
+	return ResultadoLote{Err: errOperacionDesconocida(operacion.Op)}
+}
+
+func crearSQLite(e ejecutor, producto models.Producto) (models.Producto, error) {
+	producto.CreadoEn = ahora()
+	producto.ActualizadoEn = producto.CreadoEn
+	precios, err := preciosJSON(producto.Precios)
+	if err != nil {
+		return models.Producto{}, err
+	}
+	// La categoría se comprueba en el mismo INSERT para que nadie la borre
+	// entre la comprobación y la inserción
+	res, err := e.Exec(
+		`INSERT INTO productos (sku, nombre, descripcion, categoria, categoria_id, precio_unidades, moneda, precios, stock, activo, creado_en, actualizado_en)
+		 SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		 WHERE ? = 0 OR EXISTS (SELECT 1 FROM categorias WHERE id = ?)`,
+		producto.SKU, producto.Nombre, producto.Descripcion, producto.Categoria, producto.CategoriaID, producto.Precio.Unidades(),
+		producto.Precio.Moneda(), precios, producto.Stock, producto.Activo, fecha(producto.CreadoEn), fecha(producto.ActualizadoEn),
+		producto.CategoriaID, producto.CategoriaID,
+	)
+	if err != nil {
+		return models.Producto{}, traducirError(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Producto{}, err
+	} else if n == 0 {
+		return models.Producto{}, ErrCategoriaDesconocida
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Producto{}, err
+	}
+	producto.ID = int(id)
+	producto.Version = 1
+	return producto, nil
+}
+
+func actualizarSQLite(e ejecutor, id int, producto models.Producto) (models.Producto, error) {
+	// La condición de versión va en el mismo UPDATE para que sea atómica
+	producto.ActualizadoEn = ahora()
+	precios, err := preciosJSON(producto.Precios)
+	if err != nil {
+		return models.Producto{}, err
+	}
+	var creado string
+	err = e.QueryRow(
+		`UPDATE productos SET sku = ?, nombre = ?, descripcion = ?, categoria = ?, categoria_id = ?, precio_unidades = ?, moneda = ?,
+		 	precios = ?, stock = ?, activo = ?, actualizado_en = ?, version = version + 1
+		 WHERE id = ? AND eliminado_en IS NULL AND (? = 0 OR version = ?)
+		 	AND (? = 0 OR EXISTS (SELECT 1 FROM categorias WHERE id = ?))
+		 RETURNING version, creado_en`,
+		producto.SKU, producto.Nombre, producto.Descripcion, producto.Categoria, producto.CategoriaID,
+		producto.Precio.Unidades(), producto.Precio.Moneda(),
+		precios, producto.Stock, producto.Activo, fecha(producto.ActualizadoEn),
+		id, producto.Version, producto.Version,
+		producto.CategoriaID, producto.CategoriaID,
+	).Scan(&producto.Version, &creado)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Producto{}, motivoSinActualizar(e, id, producto.Version)
+	}
+	if err != nil {
+		return models.Producto{}, traducirError(err)
+	}
+	if producto.CreadoEn, err = time.Parse(time.RFC3339Nano, creado); err != nil {
+		return models.Producto{}, fmt.Errorf("producto %d: fecha de creación inválida: %w", id, err)
+	}
+
+	producto.ID = id
+	return producto, nil
+}
+
+// eliminarSQLite envía un producto a la papelera
+func eliminarSQLite(e ejecutor, id int, version int) error {
+	eliminado := fecha(ahora())
+	res, err := e.Exec(
+		`UPDATE productos SET eliminado_en = ?, actualizado_en = ?, version = version + 1
+		 WHERE id = ? AND eliminado_en IS NULL AND (? = 0 OR version = ?)`,
+		eliminado, eliminado, id, version, version,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return motivoSinFilas(e, id)
+	}
+	return nil
+}
+
+// motivoSinFilas distingue por qué un UPDATE no afectó filas: el producto
+// no existe (o está en la papelera) o su versión no era la esperada
+func motivoSinFilas(e ejecutor, id int) error {
+	var existe bool
+	if err := e.QueryRow(`SELECT EXISTS (SELECT 1 FROM productos WHERE id = ? AND eliminado_en IS NULL)`, id).Scan(&existe); err != nil {
+		return err
+	}
+	if existe {
+		return ErrVersionNoCoincide
+	}
+	return ErrProductoNoEncontrado
+}
+
+// motivoSinActualizar es motivoSinFilas para el UPDATE de un producto, que
+// además no afecta filas si su categoría no existe
+func motivoSinActualizar(e ejecutor, id int, versionEsperada int) error {
+	var version int
+	err := e.QueryRow(`SELECT version FROM productos WHERE id = ? AND eliminado_en IS NULL`, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductoNoEncontrado
+	}
+	if err != nil {
+		return err
+	}
+	if versionEsperada != 0 && versionEsperada != version {
+		return ErrVersionNoCoincide
+	}
+	return ErrCategoriaDesconocida
+}
+
+// columnasCategoria son las columnas que lee escanearCategoria, en orden
+const columnasCategoria = `id, nombre, padre_id, creado_en, actualizado_en, version`
+
+// escanearCategoria lee una categoría seleccionada con columnasCategoria
+func escanearCategoria(f fila) (models.Categoria, error) {
+	var categoria models.Categoria
+	var creado, actualizado string
+	err := f.Scan(&categoria.ID, &categoria.Nombre, &categoria.PadreID, &creado, &actualizado, &categoria.Version)
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	if categoria.CreadoEn, err = time.Parse(time.RFC3339Nano, creado); err != nil {
+		return models.Categoria{}, fmt.Errorf("categoría %d: fecha de creación inválida: %w", categoria.ID, err)
+	}
+	if categoria.ActualizadoEn, err = time.Parse(time.RFC3339Nano, actualizado); err != nil {
+		return models.Categoria{}, fmt.Errorf("categoría %d: fecha de actualización inválida: %w", categoria.ID, err)
+	}
+	return categoria, nil
+}
+
+// leerCategorias lee todas las categorías con la función de consulta de
+// la base o de una transacción
+func leerCategorias(consultar func(query string, args ...any) (*sql.Rows, error)) (map[int]models.Categoria, error) {
+	rows, err := consultar(`SELECT ` + columnasCategoria + ` FROM categorias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categorias := make(map[int]models.Categoria)
+	for rows.Next() {
+		categoria, err := escanearCategoria(rows)
+		if err != nil {
+			return nil, err
+		}
+		categorias[categoria.ID] = categoria
+	}
+	return categorias, rows.Err()
+}
+
+// ListCategorias retorna todas las categorías ordenadas por ID
+func (r *SQLiteRepository) ListCategorias() ([]models.Categoria, error) {
+	categorias, err := leerCategorias(r.db.Query)
+	if err != nil {
+		return nil, err
+	}
+	return categoriasOrdenadas(categorias), nil
+}
+
+// GetCategoria busca una categoría por ID
+func (r *SQLiteRepository) GetCategoria(id int) (models.Categoria, error) {
+	categoria, err := escanearCategoria(r.db.QueryRow(`SELECT `+columnasCategoria+` FROM categorias WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	return categoria, err
+}
+
+// Las escrituras de categorías validan el árbol completo, así que leen
+// todas las categorías y escriben dentro de una misma transacción; con una
+// única conexión nadie más modifica la base mientras tanto.
+
+// CreateCategoria inserta una categoría; SQLite asigna el ID
+func (r *SQLiteRepository) CreateCategoria(categoria models.Categoria) (models.Categoria, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	defer tx.Rollback()
+
+	categorias, err := leerCategorias(tx.Query)
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	if err := comprobarCategoria(categorias, 0, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.Ruta = nil
+	categoria.Version = 1
+	categoria.CreadoEn = ahora()
+	categoria.ActualizadoEn = categoria.CreadoEn
+	res, err := tx.Exec(
+		`INSERT INTO categorias (nombre, padre_id, creado_en, actualizado_en) VALUES (?, ?, ?, ?)`,
+		categoria.Nombre, categoria.PadreID, fecha(categoria.CreadoEn), fecha(categoria.ActualizadoEn),
+	)
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	categoria.ID = int(id)
+
+	if err := tx.Commit(); err != nil {
+		return models.Categoria{}, err
+	}
+	return categoria, nil
+}
+
+// UpdateCategoria cambia el nombre o el padre de una categoría
+func (r *SQLiteRepository) UpdateCategoria(id int, categoria models.Categoria) (models.Categoria, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	defer tx.Rollback()
+
+	categorias, err := leerCategorias(tx.Query)
+	if err != nil {
+		return models.Categoria{}, err
+	}
+	actual, ok := categorias[id]
+	if !ok {
+		return models.Categoria{}, ErrCategoriaNoEncontrada
+	}
+	if categoria.Version != 0 && categoria.Version != actual.Version {
+		return models.Categoria{}, ErrVersionNoCoincide
+	}
+	if err := comprobarCategoria(categorias, id, categoria); err != nil {
+		return models.Categoria{}, err
+	}
+
+	categoria.ID = id
+	categoria.Ruta = nil
+	categoria.Version = actual.Version + 1
+	categoria.CreadoEn = actual.CreadoEn
+	categoria.ActualizadoEn = ahora()
+	_, err = tx.Exec(
+		`UPDATE categorias SET nombre = ?, padre_id = ?, actualizado_en = ?, version = ? WHERE id = ?`,
+		categoria.Nombre, categoria.PadreID, fecha(categoria.ActualizadoEn), categoria.Version, id,
+	)
+	if err != nil {
+		return models.Categoria{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Categoria{}, err
+	}
+	return categoria, nil
+}
+
+// DeleteCategoria elimina una categoría sin subcategorías ni productos
+func (r *SQLiteRepository) DeleteCategoria(id int, version int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	categorias, err := leerCategorias(tx.Query)
+	if err != nil {
+		return err
+	}
+	actual, ok := categorias[id]
+	if !ok {
+		return ErrCategoriaNoEncontrada
+	}
+	if version != 0 && version != actual.Version {
+		return ErrVersionNoCoincide
+	}
+	if tieneSubcategorias(categorias, id) {
+		return ErrCategoriaEnUso
+	}
+	var conProductos bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM productos WHERE categoria_id = ?)`, id).Scan(&conProductos); err != nil {
+		return err
+	}
+	if conProductos {
+		return ErrCategoriaEnUso
+	}
+
+	if _, err := tx.Exec(`DELETE FROM categorias WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,35 +1,141 @@
 package routes
 
 import (
+	"sync"
+
+	"crud-api/auth"
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/errores"
 	"crud-api/handlers"
+	"crud-api/metricas"
+	"crud-api/models"
+	"crud-api/openapi"
+	"crud-api/ratelimit"
+	"crud-api/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
-// SetupRoutes configura todas las rutas de la API
-func SetupRoutes(router *gin.Engine) {
+// Limites es la configuración del rate limiting de cada grupo de rutas
+type Limites struct {
+	Lectura   ratelimit.Config // GET de productos
+	Escritura ratelimit.Config // modificaciones y administración
+	Auth      ratelimit.Config // POST /auth/token
+}
+
+// registrarValidaciones registra una sola vez los tipos de los modelos en
+// el validador, aunque se armen varios routers
+var registrarValidaciones sync.Once
+
+// SetupRoutes configura todas las rutas de la API usando el repositorio,
+// el servicio de autenticación, los límites, las métricas, los tipos de
+// cambio y el índice de búsqueda indicados
+func SetupRoutes(router *gin.Engine, repo repository.Repositorio, autenticacion *auth.Servicio, limites Limites, m *metricas.Metricas, cambios *cambio.Cambios, indice *busqueda.Indice) {
+	// Las reglas de binding de los modelos necesitan sus tipos registrados
+	// en el validador compartido de gin
+	registrarValidaciones.Do(func() {
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			models.RegistrarValidaciones(v)
+		}
+	})
+
+	productos := handlers.NuevoProductoHandler(repo, cambios, indice)
+	categorias := handlers.NuevoCategoriaHandler(repo, productos)
+	salud := handlers.NuevoSaludHandler(repo)
+	tiposDeCambio := handlers.NuevoCambioHandler(cambios)
+
+	// Cada grupo lleva su propio limitador. Va después de identificar al
+	// cliente, para dar su propio límite a cada API key válida, pero antes
+	// de exigir la autenticación, para frenar también a quien prueba
+	// claves o contraseñas (cuentan como su IP).
+	identificar := autenticacion.Identificar()
+	lectura := ratelimit.Nuevo(limites.Lectura).Middleware()
+	escritura := ratelimit.Nuevo(limites.Escritura).Middleware()
+	login := ratelimit.Nuevo(limites.Auth).Middleware()
+
+	// Los cuerpos se validan contra el documento OpenAPI después de
+	// autenticar, así un cliente sin permiso no recibe errores de validación
+	validar := openapi.ValidarCuerpo()
+
+	// Todas las rutas responden los errores como problem+json
+	router.Use(errores.Middleware())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(errores.RutaNoEncontrada)
+	router.NoMethod(errores.MetodoNoPermitido)
+
 	// Ruta de bienvenida
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"mensaje": "¡Bienvenido al CRUD API de Productos!",
-			"versión": "1.0",
-			"endpoints": gin.H{
-				"GET /productos":       "Listar todos los productos",
-				"GET /productos/:id":   "Obtener un producto por ID",
-				"POST /productos":      "Crear un nuevo producto",
-				"PUT /productos/:id":   "Actualizar un producto",
-				"DELETE /productos/:id": "Eliminar un producto",
-			},
+			"mensaje":   "¡Bienvenido al CRUD API de Productos!",
+			"versión":   "1.0",
+			"endpoints": openapi.Endpoints(),
 		})
 	})
 
-	// Grupo de rutas para productos
+	// Documento OpenAPI; la lista de endpoints de arriba sale de él
+	router.GET("/openapi.json", openapi.Handler)
+
+	// Sondas y métricas para el balanceador y el monitoreo; sin límite
+	// de peticiones ni autenticación
+	router.GET("/healthz", salud.Vivo)
+	router.GET("/readyz", salud.Listo)
+	router.GET("/metrics", m.Handler(repo))
+
+	// Autenticación
+	router.POST("/auth/token", login, validar, autenticacion.Login)
+
+	// Administración de API keys, solo para administradores
+	adminRoutes := router.Group("/admin", identificar, escritura, autenticacion.Autenticar(), auth.RequerirRol(auth.RolAdmin), validar)
+	{
+		adminRoutes.GET("/apikeys", autenticacion.ListarAPIKeys)
+		adminRoutes.POST("/apikeys", autenticacion.CrearAPIKey)
+		adminRoutes.DELETE("/apikeys/:id", autenticacion.RevocarAPIKey)
+		adminRoutes.POST("/tipos-de-cambio/recargar", tiposDeCambio.RecargarTiposDeCambio)
+	}
+
+	// Tabla de tipos de cambio que usa ?currency=; es pública
+	router.GET("/tipos-de-cambio", identificar, lectura, tiposDeCambio.TiposDeCambio)
+
+	// Grupo de rutas para productos; las lecturas son públicas
 	productosRoutes := router.Group("/productos")
+	lecturaRoutes := productosRoutes.Group("", identificar, lectura)
+	{
+		lecturaRoutes.GET("", productos.ListarProductos)          // Listar todos
+		lecturaRoutes.GET("/:id", productos.ObtenerProducto)      // Obtener uno
+		lecturaRoutes.GET("/export", productos.ExportarProductos) // Exportar CSV/NDJSON
+		lecturaRoutes.GET("/search", productos.BuscarProductos)   // Búsqueda de texto con ranking
+	}
+
+	// Las modificaciones requieren un token o API key con rol admin o editor
+	edicionRoutes := productosRoutes.Group("", identificar, escritura, autenticacion.Autenticar(), auth.RequerirRol(auth.RolAdmin, auth.RolEditor), validar)
+	{
+		edicionRoutes.POST("", productos.CrearProducto)                 // Crear
+		edicionRoutes.POST("/bulk", productos.ProcesarLote)             // Operaciones en lote
+		edicionRoutes.POST("/import", productos.ImportarProductos)      // Importar CSV/NDJSON
+		edicionRoutes.PUT("/:id", productos.ActualizarProducto)         // Actualizar
+		edicionRoutes.PATCH("/:id", productos.ModificarProducto)        // Actualizar parcialmente
+		edicionRoutes.DELETE("/:id", productos.EliminarProducto)        // Enviar a la papelera
+		edicionRoutes.POST("/:id/restore", productos.RestaurarProducto) // Sacar de la papelera
+	}
+
+	// Taxonomía de categorías; como en productos, las lecturas son públicas
+	// y las modificaciones requieren rol admin o editor
+	categoriasRoutes := router.Group("/categorias")
+	lecturaCategorias := categoriasRoutes.Group("", identificar, lectura)
+	{
+		lecturaCategorias.GET("", categorias.ListarCategorias)
+		lecturaCategorias.GET("/:id", categorias.ObtenerCategoria)
+		lecturaCategorias.GET("/:id/productos", categorias.ProductosDeCategoria) // ?recursive=true incluye las subcategorías
+	}
+
+	edicionCategorias := categoriasRoutes.Group("", identificar, escritura, autenticacion.Autenticar(), auth.RequerirRol(auth.RolAdmin, auth.RolEditor), validar)
 	{
-		productosRoutes.GET("", handlers.ListarProductos)       // Listar todos
-		productosRoutes.GET("/:id", handlers.ObtenerProducto)   // Obtener uno
-		productosRoutes.POST("", handlers.CrearProducto)        // Crear
-		productosRoutes.PUT("/:id", handlers.ActualizarProducto) // Actualizar
-		productosRoutes.DELETE("/:id", handlers.EliminarProducto) // Eliminar
+		edicionCategorias.POST("", categorias.CrearCategoria)
+		edicionCategorias.PUT("/:id", categorias.ActualizarCategoria)
+		edicionCategorias.PATCH("/:id", categorias.ModificarCategoria) // Renombrar o mover
+		edicionCategorias.DELETE("/:id", categorias.EliminarCategoria)
 	}
 }
@@ -0,0 +1,273 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crud-api/auth"
+	"crud-api/busqueda"
+	"crud-api/cambio"
+	"crud-api/metricas"
+	"crud-api/models"
+	"crud-api/repository"
+	"crud-api/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nuevoServidor arma la API en memoria, sin límites, y retorna una API
+// key de lectura y escritura
+func nuevoServidor(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	tokens, err := auth.NuevoTokens(auth.ConfigTokens{Algoritmo: auth.AlgHS256, Secreto: auth.SecretoAleatorio(), Duracion: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claves := auth.NuevoAPIKeyStore()
+	clave, _, err := claves.Crear("test", auth.AlcanceLecturaEscritura, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cambios, err := cambio.Abrir("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cambios.Close() })
+
+	router := gin.New()
+	routes.SetupRoutes(router, repository.NuevoMemoriaRepository(), auth.NuevoServicio(auth.NuevoUsuarioStore(), tokens, claves),
+		routes.Limites{}, metricas.Nuevas(), cambios, busqueda.NuevoIndice())
+
+	servidor := httptest.NewServer(router)
+	t.Cleanup(servidor.Close)
+	return servidor, clave
+}
+
+// TestClientesConcurrentes lanza cientos de clientes en paralelo contra la
+// API en memoria; con go test -race detecta además cualquier carrera
+func TestClientesConcurrentes(t *testing.T) {
+	const clientes = 200
+	servidor, clave := nuevoServidor(t)
+
+	pedir := func(metodo, ruta, tipo, cuerpo string, destino any) error {
+		peticion, err := http.NewRequest(metodo, servidor.URL+ruta, strings.NewReader(cuerpo))
+		if err != nil {
+			return err
+		}
+		peticion.Header.Set(auth.HeaderAPIKey, clave)
+		if cuerpo != "" {
+			peticion.Header.Set("Content-Type", tipo)
+		}
+		respuesta, err := servidor.Client().Do(peticion)
+		if err != nil {
+			return err
+		}
+		defer respuesta.Body.Close()
+		if respuesta.StatusCode >= 300 {
+			return fmt.Errorf("%s %s: status %d", metodo, ruta, respuesta.StatusCode)
+		}
+		return json.NewDecoder(respuesta.Body).Decode(destino)
+	}
+
+	ids := make([]int, clientes)
+	errs := make(chan error, clientes)
+	var wg sync.WaitGroup
+	for i := 0; i < clientes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var creado models.Producto
+			cuerpo := fmt.Sprintf(`{"nombre":"Producto %d","sku":"SKU-%d","precio":"1.50","stock":1}`, i, i)
+			if err := pedir(http.MethodPost, "/productos", "application/json", cuerpo, &creado); err != nil {
+				errs <- err
+				return
+			}
+			ids[i] = creado.ID
+
+			var listado, resultados map[string]any
+			var modificado models.Producto
+			switch {
+			case pedir(http.MethodGet, "/productos?limit=10", "", "", &listado) != nil,
+				pedir(http.MethodGet, "/productos/search?q=producto", "", "", &resultados) != nil:
+				errs <- fmt.Errorf("cliente %d: falló una lectura", i)
+			case pedir(http.MethodPatch, fmt.Sprintf("/productos/%d", creado.ID), "application/merge-patch+json", `{"stock":2}`, &modificado) != nil:
+				errs <- fmt.Errorf("cliente %d: falló el PATCH", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if t.Failed() {
+		return
+	}
+
+	// Cada producto tiene un ID distinto y ninguno se perdió
+	vistos := make(map[int]bool, clientes)
+	for i, id := range ids {
+		if vistos[id] {
+			t.Fatalf("ID %d repetido", id)
+		}
+		vistos[id] = true
+
+		var producto models.Producto
+		if err := pedir(http.MethodGet, fmt.Sprintf("/productos/%d", id), "", "", &producto); err != nil {
+			t.Fatal(err)
+		}
+		if producto.SKU != fmt.Sprintf("SKU-%d", i) || producto.Stock != 2 {
+			t.Fatalf("producto %d: sku %q, stock %d", id, producto.SKU, producto.Stock)
+		}
+	}
+
+	var listado struct {
+		Total int `json:"total"`
+	}
+	if err := pedir(http.MethodGet, "/productos", "", "", &listado); err != nil {
+		t.Fatal(err)
+	}
+	if listado.Total != clientes {
+		t.Fatalf("total %d, se esperaban %d", listado.Total, clientes)
+	}
+}
+
+// TestPrecioMayorQueCero comprueba que las reglas de binding de Precio se
+// aplican al importe y no al struct de dinero.Dinero
+func TestPrecioMayorQueCero(t *testing.T) {
+	servidor, clave := nuevoServidor(t)
+
+	peticion, err := http.NewRequest(http.MethodPost, servidor.URL+"/productos", strings.NewReader(`{"nombre":"Gratis","precio":"0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peticion.Header.Set(auth.HeaderAPIKey, clave)
+	peticion.Header.Set("Content-Type", "application/json")
+	respuesta, err := servidor.Client().Do(peticion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respuesta.Body.Close()
+
+	var problema struct {
+		Errores []struct {
+			Campo   string `json:"campo"`
+			Mensaje string `json:"mensaje"`
+		} `json:"errores"`
+	}
+	if err := json.NewDecoder(respuesta.Body).Decode(&problema); err != nil {
+		t.Fatal(err)
+	}
+	if respuesta.StatusCode != http.StatusBadRequest || len(problema.Errores) != 1 || problema.Errores[0].Campo != "precio" {
+		t.Fatalf("status %d, errores %+v; se esperaba 400 en precio", respuesta.StatusCode, problema.Errores)
+	}
+}
+
+// TestPatchValidaElCuerpo comprueba que un PATCH se valida contra el
+// documento y se limita a 16 MB con cualquiera de los Content-Type que
+// acepta el handler
+func TestPatchValidaElCuerpo(t *testing.T) {
+	servidor, clave := nuevoServidor(t)
+	enviar := func(metodo, ruta, tipo, cuerpo string) int {
+		t.Helper()
+		peticion, err := http.NewRequest(metodo, servidor.URL+ruta, strings.NewReader(cuerpo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		peticion.Header.Set(auth.HeaderAPIKey, clave)
+		peticion.Header.Set("Content-Type", tipo)
+		respuesta, err := servidor.Client().Do(peticion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		respuesta.Body.Close()
+		return respuesta.StatusCode
+	}
+
+	if status := enviar(http.MethodPost, "/productos", "application/json", `{"nombre":"Laptop","precio":"899.99"}`); status != http.StatusCreated {
+		t.Fatalf("crear: status %d", status)
+	}
+
+	grande := `{"descripcion":"` + strings.Repeat("a", 17<<20) + `"}`
+	for _, tipo := range []string{"application/json", "application/json; charset=utf-8", "Application/JSON", "application/merge-patch+json", "APPLICATION/MERGE-PATCH+JSON"} {
+		if status := enviar(http.MethodPatch, "/productos/1", tipo, `{"desconocido":1}`); status != http.StatusBadRequest {
+			t.Errorf("%s con un campo desconocido: status %d, se esperaba 400", tipo, status)
+		}
+		if status := enviar(http.MethodPatch, "/productos/1", tipo, grande); status != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s de 17 MB: status %d, se esperaba 413", tipo, status)
+		}
+	}
+}
+
+// TestCSVIdaYVuelta exporta productos cuyos textos empiezan con caracteres
+// de fórmula o con apóstrofo e importa el CSV en otra API: los textos
+// tienen que llegar iguales
+func TestCSVIdaYVuelta(t *testing.T) {
+	textos := []string{"'=SUMA(A1)", "=HYPERLINK(\"x\")", "+54 11", "-5", "@usuario", "'hola", "''", "'", "normal"}
+
+	enviar := func(servidor *httptest.Server, clave, metodo, ruta, tipo, cuerpo string) (int, string) {
+		t.Helper()
+		peticion, err := http.NewRequest(metodo, servidor.URL+ruta, strings.NewReader(cuerpo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		peticion.Header.Set(auth.HeaderAPIKey, clave)
+		if cuerpo != "" {
+			peticion.Header.Set("Content-Type", tipo)
+		}
+		respuesta, err := servidor.Client().Do(peticion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer respuesta.Body.Close()
+		var texto strings.Builder
+		if _, err := io.Copy(&texto, respuesta.Body); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta.StatusCode, texto.String()
+	}
+
+	origen, claveOrigen := nuevoServidor(t)
+	for i, texto := range textos {
+		cuerpo, err := json.Marshal(map[string]string{"sku": texto + strconv.Itoa(i), "nombre": texto, "descripcion": texto, "precio": "1.00"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status, respuesta := enviar(origen, claveOrigen, http.MethodPost, "/productos", "application/json", string(cuerpo)); status != http.StatusCreated {
+			t.Fatalf("crear %q: status %d: %s", texto, status, respuesta)
+		}
+	}
+
+	status, exportado := enviar(origen, claveOrigen, http.MethodGet, "/productos/export?format=csv", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("exportar: status %d", status)
+	}
+	if !strings.Contains(exportado, "'=HYPERLINK") || !strings.Contains(exportado, "''=SUMA(A1)") {
+		t.Fatalf("la exportación no escapa las fórmulas:\n%s", exportado)
+	}
+
+	destino, claveDestino := nuevoServidor(t)
+	if status, respuesta := enviar(destino, claveDestino, http.MethodPost, "/productos/import", "text/csv", exportado); status >= 300 {
+		t.Fatalf("importar: status %d: %s", status, respuesta)
+	}
+	for i, texto := range textos {
+		var producto models.Producto
+		_, respuesta := enviar(destino, claveDestino, http.MethodGet, fmt.Sprintf("/productos/%d", i+1), "", "")
+		if err := json.Unmarshal([]byte(respuesta), &producto); err != nil {
+			t.Fatalf("producto %d: %v: %s", i+1, err, respuesta)
+		}
+		if producto.Nombre != texto || producto.Descripcion != texto || producto.SKU != texto+strconv.Itoa(i) {
+			t.Errorf("producto %d: nombre %q, descripción %q, sku %q; se esperaba %q", i+1, producto.Nombre, producto.Descripcion, producto.SKU, texto)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+module crud
+
+go 1.21
+
+require crud-api v0.0.0
+
+// dinero se comparte con la API; se usa la copia local del repositorio
+replace crud-api => ../crud-api
@@ -6,13 +6,18 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"crud-api/dinero"
 )
 
+// moneda es la moneda de todos los precios de este ejemplo
+const moneda = "USD"
+
 // Producto representa un producto en nuestro sistema
 type Producto struct {
 	ID     int
 	Nombre string
-	Precio float64
+	Precio dinero.Dinero // exacto, en centavos; nunca float64
 }
 
 // Variable global para almacenar productos (en memoria)
@@ -20,7 +25,7 @@ var productos []Producto
 var siguienteID int = 1
 
 // CREATE - Crear un nuevo producto
-func crearProducto(nombre string, precio float64) {
+func crearProducto(nombre string, precio dinero.Dinero) {
 	nuevoProducto := Producto{
 		ID:     siguienteID,
 		Nombre: nombre,
@@ -42,7 +47,7 @@ func listarProductos() {
 	fmt.Printf("%-5s %-20s %-10s\n", "ID", "Nombre", "Precio")
 	fmt.Println(strings.Repeat("-", 40))
 	for _, p := range productos {
-		fmt.Printf("%-5d %-20s $%-9.2f\n", p.ID, p.Nombre, p.Precio)
+		fmt.Printf("%-5d %-20s $%-9s\n", p.ID, p.Nombre, p.Precio.Decimal())
 	}
 	fmt.Println()
 }
@@ -58,7 +63,7 @@ func buscarProducto(id int) *Producto {
 }
 
 // UPDATE - Actualizar un producto existente
-func actualizarProducto(id int, nombre string, precio float64) bool {
+func actualizarProducto(id int, nombre string, precio dinero.Dinero) bool {
 	producto := buscarProducto(id)
 	if producto == nil {
 		fmt.Println("✗ Producto no encontrado.")
@@ -115,9 +120,9 @@ func main() {
 			// CREATE
 			nombre := leerTexto(scanner, "Nombre del producto: ")
 			precioStr := leerTexto(scanner, "Precio: ")
-			precio, err := strconv.ParseFloat(precioStr, 64)
+			precio, err := dinero.Parsear(precioStr, moneda)
 			if err != nil {
-				fmt.Println("✗ Error: precio inválido")
+				fmt.Println("✗ Error: el precio", err)
 				continue
 			}
 			crearProducto(nombre, precio)
@@ -139,7 +144,7 @@ func main() {
 				fmt.Printf("\n--- Producto encontrado ---\n")
 				fmt.Printf("ID:     %d\n", producto.ID)
 				fmt.Printf("Nombre: %s\n", producto.Nombre)
-				fmt.Printf("Precio: $%.2f\n\n", producto.Precio)
+				fmt.Printf("Precio: $%s\n\n", producto.Precio.Decimal())
 			} else {
 				fmt.Println("✗ Producto no encontrado.")
 			}
@@ -161,9 +166,9 @@ func main() {
 
 			nombre := leerTexto(scanner, "Nuevo nombre: ")
 			precioStr := leerTexto(scanner, "Nuevo precio: ")
-			precio, err := strconv.ParseFloat(precioStr, 64)
+			precio, err := dinero.Parsear(precioStr, moneda)
 			if err != nil {
-				fmt.Println("✗ Error: precio inválido")
+				fmt.Println("✗ Error: el precio", err)
 				continue
 			}
 			actualizarProducto(id, nombre, precio)
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
+
+	"crud-api/dinero"
 )
 
 // ========== EJEMPLO COMPLETO: SISTEMA DE PROCESAMIENTO DE PEDIDOS ==========
@@ -14,6 +17,7 @@ import (
 // - Goroutines y channels
 // - Manejo de errores
 // - Concurrencia con WaitGroup
+// - Dinero exacto: los importes son enteros de centavos, no float64
 
 // ========== TIPOS Y ESTRUCTURAS ==========
 
@@ -39,9 +43,12 @@ type Pedido struct {
 	Producto string
 	Cantidad int
 	Estado   EstadoPedido
-	Precio   float64
+	Precio   dinero.Dinero
 }
 
+// moneda es la moneda de todos los pedidos del ejemplo
+const moneda = "USD"
+
 // Resultado del procesamiento
 type Resultado struct {
 	Pedido  *Pedido
@@ -229,13 +236,19 @@ func GenerarPedidos(cantidad int) []*Pedido {
 			Producto: productos[rand.Intn(len(productos))],
 			Cantidad: rand.Intn(3) + 1, // 1-3 unidades
 			Estado:   Pendiente,
-			Precio:   float64(rand.Intn(1000) + 100),
+			Precio:   precioAleatorio(),
 		}
 	}
 
 	return pedidos
 }
 
+// precioAleatorio retorna un precio entre $100.00 y $1099.99
+func precioAleatorio() dinero.Dinero {
+	precio, _ := dinero.Nuevo(int64(rand.Intn(100000)+10000), moneda)
+	return precio
+}
+
 // MostrarEstadisticas muestra el resumen de resultados
 func MostrarEstadisticas(resultados []Resultado) {
 	fmt.Println("\n" + strings.Repeat("=", 60))
@@ -244,12 +257,19 @@ func MostrarEstadisticas(resultados []Resultado) {
 
 	exitosos := 0
 	fallidos := 0
-	totalVentas := 0.0
+	// Sumar float64 acumula errores de redondeo (0.1 + 0.2 != 0.3);
+	// con dinero la suma es exacta
+	totalVentas, _ := dinero.Nuevo(0, moneda)
 
 	for _, res := range resultados {
 		if res.Exito {
 			exitosos++
-			totalVentas += res.Pedido.Precio
+			total, err := totalVentas.Sumar(res.Pedido.Precio)
+			if err != nil {
+				fmt.Println("Error al sumar las ventas:", err)
+				return
+			}
+			totalVentas = total
 		} else {
 			fallidos++
 		}
@@ -258,7 +278,7 @@ func MostrarEstadisticas(resultados []Resultado) {
 	fmt.Printf("Total de pedidos:    %d\n", len(resultados))
 	fmt.Printf("Exitosos:            %d (%.1f%%)\n", exitosos, float64(exitosos)/float64(len(resultados))*100)
 	fmt.Printf("Fallidos:            %d (%.1f%%)\n", fallidos, float64(fallidos)/float64(len(resultados))*100)
-	fmt.Printf("Total de ventas:     $%.2f\n", totalVentas)
+	fmt.Printf("Total de ventas:     $%s\n", totalVentas.Decimal())
 
 	fmt.Println("\nDetalle de pedidos fallidos:")
 	for _, res := range resultados {
@@ -270,8 +290,6 @@ func MostrarEstadisticas(resultados []Resultado) {
 
 // ========== MAIN ==========
 
-import "strings"
-
 func main() {
 	fmt.Println("========================================")
 	fmt.Println("SISTEMA DE PROCESAMIENTO DE PEDIDOS")
@@ -0,0 +1,9 @@
+module ejercicios
+
+go 1.21
+
+require crud-api v0.0.0
+
+// El ejemplo completo usa el tipo dinero de la API; se usa la copia local
+// del repositorio
+replace crud-api => ../crud-api